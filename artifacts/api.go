@@ -0,0 +1,91 @@
+// Package artifacts collects screenshots and videos written by specs and uploads them
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+var apiURI = "/api/v1/executions/artifacts"
+
+// API uploads artifacts to cypress-parallel-api with multipart requests
+type API struct {
+	URL string // HTTP(s) url of the artifacts endpoint
+}
+
+// Upload posts the file in the file field named after key with metadata as form fields.
+// The api answers with the url of the artifact like {"url": "https://..."}
+func (a API) Upload(ctx context.Context, key string, file string, metadata map[string]string) (string, error) {
+	// the form is streamed so videos are not held in memory, with the same boundary on each attempt
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	body := func() (io.Reader, error) {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		pr, pw := io.Pipe()
+		w := multipart.NewWriter(pw)
+		if err = w.SetBoundary(boundary); err != nil {
+			f.Close()
+			return nil, err
+		}
+		go func() {
+			defer f.Close()
+			pw.CloseWithError(writeForm(w, key, f, metadata))
+		}()
+		return pr, nil
+	}
+	req, err := retryablehttp.NewRequestWithContext(ctx, "POST", a.URL, retryablehttp.ReaderFunc(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
+	req.Header.Set("Accept", "application/json")
+	b, resp, err := do(req)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("unexpected status code %d while uploading artifact %s", resp.StatusCode, key)
+	}
+	var z struct {
+		URL string `json:"url"`
+	}
+	if err = json.Unmarshal(b, &z); err != nil {
+		return "", fmt.Errorf("invalid response while uploading artifact %s: %w", key, err)
+	}
+	if z.URL == "" {
+		return "", fmt.Errorf("no url returned while uploading artifact %s", key)
+	}
+	return z.URL, nil
+}
+
+// writeForm writes the metadata fields sorted by name then the file field named after key
+func writeForm(w *multipart.Writer, key string, f io.Reader, metadata map[string]string) error {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := w.WriteField(k, metadata[k]); err != nil {
+			return err
+		}
+	}
+	part, err := w.CreateFormFile("file", key)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(part, f); err != nil {
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,95 @@
+// Package artifacts collects screenshots and videos written by specs and uploads them
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPI_upload(t *testing.T) {
+	assert := assert.New(t)
+	var (
+		filename, content, spec string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		b, _ := io.ReadAll(f)
+		filename, content, spec = header.Filename, string(b), r.FormValue("spec")
+		fmt.Fprintf(w, `{"url": "https://artifacts.example.com/%s"}`, header.Filename)
+	}))
+	defer ts.Close()
+
+	file := filepath.Join(t.TempDir(), "login.cy.js.mp4")
+	assert.NoError(os.WriteFile(file, []byte("mp4"), 0644))
+	z, err := API{URL: ts.URL}.Upload(context.Background(), "uid/login/videos/login.cy.js.mp4", file, map[string]string{"spec": "cypress/e2e/login.cy.js"})
+	assert.NoError(err)
+	assert.Equal("https://artifacts.example.com/login.cy.js.mp4", z)
+	assert.Equal("login.cy.js.mp4", filename)
+	assert.Equal("mp4", content)
+	assert.Equal("cypress/e2e/login.cy.js", spec)
+
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "{}")
+	})
+	_, err = API{URL: ts.URL}.Upload(context.Background(), "uid/login/videos/login.cy.js.mp4", file, nil)
+	assert.Error(err)
+
+	_, err = API{URL: ts.URL}.Upload(context.Background(), "uid/login/videos/login.cy.js.mp4", filepath.Join(t.TempDir(), "missing.mp4"), nil)
+	assert.Error(err)
+}
+
+func TestAPI_upload_retry(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("HTTP_RETRY_WAIT_MIN", "1")
+	t.Setenv("HTTP_RETRY_WAIT_MAX", "1")
+	var attempts int
+	var content string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		if attempts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		b, _ := io.ReadAll(f)
+		content = string(b)
+		fmt.Fprintln(w, `{"url": "https://artifacts.example.com/login.cy.js.mp4"}`)
+	}))
+	defer ts.Close()
+
+	file := filepath.Join(t.TempDir(), "login.cy.js.mp4")
+	assert.NoError(os.WriteFile(file, []byte("mp4"), 0644))
+	z, err := API{URL: ts.URL}.Upload(context.Background(), "uid/login/videos/login.cy.js.mp4", file, nil)
+	assert.NoError(err)
+	assert.Equal("https://artifacts.example.com/login.cy.js.mp4", z)
+	assert.Equal(2, attempts)
+	assert.Equal("mp4", content)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = API{URL: ts.URL}.Upload(ctx, "uid/login/videos/login.cy.js.mp4", file, nil)
+	assert.Error(err)
+	assert.Equal(2, attempts)
+}
@@ -0,0 +1,127 @@
+// Package artifacts collects screenshots and videos written by specs and uploads them
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Lord-Y/cypress-parallel-cli/httprequests"
+	"github.com/Lord-Y/cypress-parallel-cli/logger"
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/rs/zerolog/log"
+)
+
+// File is an artifact written by a spec
+type File struct {
+	Path string // Path of the file on disk
+	Key  string // Slash separated path of the file relative to the collected directory
+	Size int64  // Size of the file in bytes
+}
+
+// Uploader uploads artifacts
+type Uploader interface {
+	// Upload uploads the file under key with the given metadata and returns its url
+	Upload(ctx context.Context, key string, file string, metadata map[string]string) (string, error)
+}
+
+func init() {
+	logger.SetLoggerLogLevel()
+}
+
+// uploadTimeout is the maximum duration of each attempt to upload an artifact
+var uploadTimeout = 5 * time.Minute
+
+// do performs the request with the retries of httprequests and returns the response body.
+// Bodies are read again from disk on each attempt. Requests are not logged
+// as their headers carry credentials and their bodies are binary files
+func do(req *retryablehttp.Request) (body []byte, resp *http.Response, err error) {
+	client := httprequests.RetryClient()
+	client.HTTPClient.Timeout = uploadTimeout
+	resp, err = client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body, err = io.ReadAll(resp.Body)
+	log.Debug().Msgf("%s %s statusCode %d", req.Method, req.URL.Redacted(), resp.StatusCode)
+	return
+}
+
+// NewUploader returns the uploader of the destination.
+// Destination is api to upload artifacts to apiURL or s3://bucket/prefix to upload them
+// to an S3 compatible bucket reachable at endpoint, AWS when endpoint is empty
+func NewUploader(destination string, apiURL string, endpoint string) (Uploader, error) {
+	if destination == "api" {
+		if apiURL == "" {
+			return nil, fmt.Errorf("api url is required to upload artifacts to the api")
+		}
+		return API{URL: fmt.Sprintf("%s%s", apiURL, apiURI)}, nil
+	}
+	if strings.HasPrefix(destination, "s3://") {
+		return NewS3(destination, endpoint)
+	}
+	return nil, fmt.Errorf("unknown artifacts destination %s, it must be api or s3://bucket/prefix", destination)
+}
+
+// Collect returns the files of dir sorted by key, nothing when dir does not exist
+func Collect(dir string) (z []File, err error) {
+	err = filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && file == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		z = append(z, File{Path: file, Key: filepath.ToSlash(rel), Size: info.Size()})
+		return nil
+	})
+	sort.Slice(z, func(i, j int) bool {
+		return z[i].Key < z[j].Key
+	})
+	return
+}
+
+// Upload uploads the files under prefix and returns their urls.
+// Files are skipped once maxSize bytes have been uploaded, 0 means unlimited.
+// Files failing to be uploaded are logged and skipped, remaining ones are skipped once ctx is done
+func Upload(ctx context.Context, u Uploader, prefix string, files []File, metadata map[string]string, maxSize int64) (z []string) {
+	var size int64
+	for _, f := range files {
+		if ctx.Err() != nil {
+			log.Warn().Err(ctx.Err()).Msgf("Artifact %s skipped", f.Path)
+			continue
+		}
+		if maxSize > 0 && size+f.Size > maxSize {
+			log.Warn().Msgf("Artifact %s of %d bytes skipped, size limit of %d bytes reached", f.Path, f.Size, maxSize)
+			continue
+		}
+		url, err := u.Upload(ctx, path.Join(prefix, f.Key), f.Path, metadata)
+		if err != nil {
+			log.Error().Err(err).Msgf("Error occured while uploading artifact %s", f.Path)
+			continue
+		}
+		size += f.Size
+		z = append(z, url)
+	}
+	return
+}
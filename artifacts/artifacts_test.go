@@ -0,0 +1,89 @@
+// Package artifacts collects screenshots and videos written by specs and uploads them
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder is an uploader recording uploaded keys
+type recorder struct {
+	keys []string
+}
+
+func (r *recorder) Upload(ctx context.Context, key string, file string, metadata map[string]string) (string, error) {
+	if filepath.Base(file) == "broken.png" {
+		return "", fmt.Errorf("upload failed")
+	}
+	r.keys = append(r.keys, key)
+	return fmt.Sprintf("https://artifacts.example.com/%s", key), nil
+}
+
+func TestCollect(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	for file, content := range map[string]string{
+		"videos/login.cy.js.mp4":                   "0123456789",
+		"screenshots/login.cy.js/login (fail).png": "01234",
+	} {
+		assert.NoError(os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), 0755))
+		assert.NoError(os.WriteFile(filepath.Join(dir, file), []byte(content), 0644))
+	}
+
+	z, err := Collect(dir)
+	assert.NoError(err)
+	assert.Equal([]File{
+		{Path: filepath.Join(dir, "screenshots", "login.cy.js", "login (fail).png"), Key: "screenshots/login.cy.js/login (fail).png", Size: 5},
+		{Path: filepath.Join(dir, "videos", "login.cy.js.mp4"), Key: "videos/login.cy.js.mp4", Size: 10},
+	}, z)
+
+	z, err = Collect(filepath.Join(dir, "missing"))
+	assert.NoError(err)
+	assert.Empty(z)
+}
+
+func TestUpload(t *testing.T) {
+	files := []File{
+		{Path: "/tmp/a.png", Key: "a.png", Size: 5},
+		{Path: "/tmp/broken.png", Key: "broken.png", Size: 1},
+		{Path: "/tmp/b.mp4", Key: "b.mp4", Size: 10},
+		{Path: "/tmp/c.png", Key: "c.png", Size: 5},
+	}
+	tests := []struct {
+		maxSize  int64
+		expected []string
+	}{
+		{
+			expected: []string{"uid/a.png", "uid/b.mp4", "uid/c.png"},
+		},
+		{
+			maxSize:  12,
+			expected: []string{"uid/a.png", "uid/c.png"},
+		},
+	}
+
+	assert := assert.New(t)
+	for _, tc := range tests {
+		r := &recorder{}
+		z := Upload(context.Background(), r, "uid", files, nil, tc.maxSize)
+		assert.Equal(tc.expected, r.keys)
+		assert.Len(z, len(tc.expected))
+	}
+}
+
+func TestNewUploader(t *testing.T) {
+	assert := assert.New(t)
+	z, err := NewUploader("api", "http://localhost:8080", "")
+	assert.NoError(err)
+	assert.Equal(API{URL: "http://localhost:8080/api/v1/executions/artifacts"}, z)
+
+	_, err = NewUploader("api", "", "")
+	assert.Error(err)
+	_, err = NewUploader("gs://artifacts", "http://localhost:8080", "")
+	assert.Error(err)
+}
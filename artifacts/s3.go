@@ -0,0 +1,195 @@
+// Package artifacts collects screenshots and videos written by specs and uploads them
+package artifacts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// S3 uploads artifacts to an S3 compatible bucket like AWS S3 or MinIO.
+// Objects are addressed with path style urls and requests are signed with AWS signature version 4
+type S3 struct {
+	Endpoint     string // HTTP(s) url of the S3 api like http://localhost:9000
+	Bucket       string // Bucket in which artifacts are uploaded
+	Prefix       string // Prefix of the object keys
+	Region       string // Region of the bucket
+	AccessKey    string // Access key id
+	SecretKey    string // Secret access key
+	SessionToken string // Session token of temporary credentials, optional
+
+	now func() time.Time // time of the signature, time.Now when nil
+}
+
+// NewS3 returns the S3 uploader of destination formatted like s3://bucket/prefix.
+// Credentials and region are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN
+// and AWS_REGION or AWS_DEFAULT_REGION. Endpoint defaults to AWS_ENDPOINT_URL, then to the AWS endpoint of the region
+func NewS3(destination string, endpoint string) (z S3, err error) {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return z, fmt.Errorf("invalid s3 destination %s, it must be formatted like s3://bucket/prefix", destination)
+	}
+	z.Bucket = u.Host
+	z.Prefix = strings.Trim(u.Path, "/")
+	z.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	z.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	z.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	if z.AccessKey == "" || z.SecretKey == "" {
+		return z, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required to upload artifacts to %s", destination)
+	}
+	for _, region := range []string{os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1"} {
+		if region != "" {
+			z.Region = region
+			break
+		}
+	}
+	z.Endpoint = endpoint
+	if z.Endpoint == "" {
+		z.Endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if z.Endpoint == "" {
+		z.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", z.Region)
+	}
+	z.Endpoint = strings.TrimSuffix(z.Endpoint, "/")
+	return
+}
+
+// Upload puts the file in the bucket with metadata as x-amz-meta headers and returns its url
+func (s S3) Upload(ctx context.Context, key string, file string, metadata map[string]string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	// the payload is hashed first then streamed as the signature covers it
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	object := fmt.Sprintf("%s%s", s.Endpoint, escapePath(path.Join("/", s.Bucket, s.Prefix, key)))
+	u, err := url.Parse(object)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	headers := make(map[string]string)
+	headers["Content-Type"] = contentType
+	headers["x-amz-content-sha256"] = hex.EncodeToString(h.Sum(nil))
+	headers["x-amz-date"] = now().UTC().Format("20060102T150405Z")
+	if s.SessionToken != "" {
+		headers["x-amz-security-token"] = s.SessionToken
+	}
+	for k, v := range metadata {
+		headers[fmt.Sprintf("x-amz-meta-%s", strings.ToLower(k))] = v
+	}
+	headers["Authorization"] = s.authorization("PUT", u, headers)
+
+	body := func() (io.Reader, error) {
+		return os.Open(file)
+	}
+	req, err := retryablehttp.NewRequestWithContext(ctx, "PUT", object, retryablehttp.ReaderFunc(body))
+	if err != nil {
+		return "", err
+	}
+	// S3 requires the length of the object, requests without it are chunked
+	req.ContentLength = size
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	_, resp, err := do(req)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d while uploading artifact %s", resp.StatusCode, key)
+	}
+	return object, nil
+}
+
+// authorization returns the AWS signature version 4 authorization header of the request.
+// Headers must contain x-amz-date and x-amz-content-sha256, they are all signed with the host
+func (s S3) authorization(method string, u *url.URL, headers map[string]string) string {
+	values := map[string]string{"host": u.Host}
+	for k, v := range headers {
+		values[strings.ToLower(k)] = strings.TrimSpace(v)
+	}
+	names := make([]string, 0, len(values))
+	for k := range values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", k, values[k])
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		u.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		values["x-amz-content-sha256"],
+	}, "\n")
+
+	date := values["x-amz-date"][:8]
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.Region)
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", values["x-amz-date"], scope, hashHex([]byte(canonicalRequest))}, "\n")
+	key := []byte("AWS4" + s.SecretKey)
+	for _, v := range []string{date, s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, v)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	return fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s", s.AccessKey, scope, signedHeaders, signature)
+}
+
+// escapePath escapes the object path as expected by S3 signatures, slashes are kept
+func escapePath(p string) string {
+	var b strings.Builder
+	for _, c := range []byte(p) {
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// hashHex returns the hex encoded sha256 of content
+func hashHex(content []byte) string {
+	z := sha256.Sum256(content)
+	return hex.EncodeToString(z[:])
+}
+
+// hmacSHA256 returns the hmac sha256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,93 @@
+// Package cgroup reads cpu and memory limits applied to the current container
+package cgroup
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Lord-Y/cypress-parallel-cli/logger"
+	"github.com/rs/zerolog/log"
+)
+
+// root is the mount point of the cgroup filesystem
+var root = "/sys/fs/cgroup"
+
+// unlimitedMemory is the value reported by cgroup v1 when no memory limit is set
+const unlimitedMemory = 1 << 62
+
+func init() {
+	logger.SetLoggerLogLevel()
+}
+
+// CPU returns the number of cpus allowed by the cgroup or 0 when there is no limit
+func CPU() (z float64) {
+	// cgroup v2
+	if content, err := readFile("cpu.max"); err == nil {
+		fields := strings.Fields(content)
+		if len(fields) != 2 || fields[0] == "max" {
+			return
+		}
+		return quota(fields[0], fields[1])
+	}
+
+	// cgroup v1
+	q, err := readFile("cpu/cpu.cfs_quota_us")
+	if err != nil {
+		return
+	}
+	p, err := readFile("cpu/cpu.cfs_period_us")
+	if err != nil {
+		return
+	}
+	return quota(q, p)
+}
+
+// Memory returns the memory limit in bytes or 0 when there is no limit
+func Memory() (z int64) {
+	// cgroup v2
+	content, err := readFile("memory.max")
+	if err != nil {
+		// cgroup v1
+		content, err = readFile("memory/memory.limit_in_bytes")
+		if err != nil {
+			return
+		}
+	}
+	if content == "max" {
+		return
+	}
+
+	z, err = strconv.ParseInt(content, 10, 64)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Error occured while parsing cgroup memory limit %s", content)
+		return 0
+	}
+	if z <= 0 || z >= unlimitedMemory {
+		return 0
+	}
+	return
+}
+
+// quota converts cpu quota and period into a number of cpus
+func quota(q, p string) (z float64) {
+	qi, err := strconv.ParseInt(q, 10, 64)
+	if err != nil || qi <= 0 {
+		return
+	}
+	pi, err := strconv.ParseInt(p, 10, 64)
+	if err != nil || pi <= 0 {
+		return
+	}
+	return float64(qi) / float64(pi)
+}
+
+// readFile returns the trimmed content of the cgroup file
+func readFile(name string) (z string, err error) {
+	content, err := os.ReadFile(filepath.Join(root, name))
+	if err != nil {
+		return
+	}
+	return strings.TrimSpace(string(content)), nil
+}
@@ -0,0 +1,113 @@
+// Package cgroup reads cpu and memory limits applied to the current container
+package cgroup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, files map[string]string) {
+	root = t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCPU(t *testing.T) {
+	assert := assert.New(t)
+	defer func(r string) { root = r }(root)
+
+	tests := []struct {
+		files    map[string]string
+		expected float64
+	}{
+		{
+			files:    map[string]string{"cpu.max": "400000 100000\n"},
+			expected: 4,
+		},
+		{
+			files:    map[string]string{"cpu.max": "150000 100000\n"},
+			expected: 1.5,
+		},
+		{
+			files:    map[string]string{"cpu.max": "50000 100000\n"},
+			expected: 0.5,
+		},
+		{
+			files:    map[string]string{"cpu.max": "max 100000\n"},
+			expected: 0,
+		},
+		{
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":  "200000\n",
+				"cpu/cpu.cfs_period_us": "100000\n",
+			},
+			expected: 2,
+		},
+		{
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":  "-1\n",
+				"cpu/cpu.cfs_period_us": "100000\n",
+			},
+			expected: 0,
+		},
+		{
+			files:    map[string]string{},
+			expected: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		writeFiles(t, tc.files)
+		assert.Equal(tc.expected, CPU())
+	}
+}
+
+func TestMemory(t *testing.T) {
+	assert := assert.New(t)
+	defer func(r string) { root = r }(root)
+
+	tests := []struct {
+		files    map[string]string
+		expected int64
+	}{
+		{
+			files:    map[string]string{"memory.max": "4294967296\n"},
+			expected: 4294967296,
+		},
+		{
+			files:    map[string]string{"memory.max": "max\n"},
+			expected: 0,
+		},
+		{
+			files:    map[string]string{"memory/memory.limit_in_bytes": "2147483648\n"},
+			expected: 2147483648,
+		},
+		{
+			files:    map[string]string{"memory/memory.limit_in_bytes": "9223372036854771712\n"},
+			expected: 0,
+		},
+		{
+			files:    map[string]string{"memory.max": "bad\n"},
+			expected: 0,
+		},
+		{
+			files:    map[string]string{},
+			expected: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		writeFiles(t, tc.files)
+		assert.Equal(tc.expected, Memory())
+	}
+}
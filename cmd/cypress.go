@@ -2,6 +2,7 @@
 package cmd
 
 import (
+	"github.com/Lord-Y/cypress-parallel-cli/cmd/cypress"
 	"github.com/urfave/cli/v2"
 )
 
@@ -10,62 +11,13 @@ func Cypress(c *cli.Context) (z *cli.Command) {
 	return &cli.Command{
 		Name:  "cypress",
 		Usage: "options related to cypress command",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:        "api-url",
-				Aliases:     []string{"a"},
-				Value:       "http://127.0.0.1:8080",
-				Usage:       "HTTP(s) api url of cypress-parallel",
-				Destination: &cmd.ApiURL,
-			},
-			&cli.StringFlag{
-				Name:        "repository",
-				Aliases:     []string{"r"},
-				Value:       "",
-				Required:    true,
-				Usage:       "HTTP(s) git repository (required)",
-				Destination: &cmd.Repository,
-			},
-			&cli.StringFlag{
-				Name:        "username",
-				Aliases:     []string{"u"},
-				Value:       "",
-				Usage:       "Username to used to fetch repository if required",
-				Destination: &cmd.Username,
-			},
-			&cli.StringFlag{
-				Name:        "password",
-				Aliases:     []string{"p"},
-				Value:       "",
-				Usage:       "Password to used to fetch repository if required",
-				Destination: &cmd.Password,
-			},
-			&cli.StringFlag{
-				Name:        "branch",
-				Aliases:     []string{"b"},
-				Value:       "",
-				Usage:       "Ref (Branch ex: master or tag ex: refs/tags/6.0.0) in which specs are hold (required)",
-				Destination: &cmd.Branch,
-			},
-			&cli.StringFlag{
-				Name:        "specs",
-				Aliases:     []string{"s"},
-				Required:    true,
-				Usage:       "Comma separated list of specs (required)",
-				Destination: &cmd.Specs,
-			},
-			&cli.StringFlag{
-				Name:        "uniq-id",
-				Aliases:     []string{"uid"},
-				Required:    true,
-				Usage:       "Uniq ID to run cypress command (required)",
-				Destination: &cmd.UniqID,
-			},
+		Flags: append(
+			runFlags(&cmd),
 			&cli.StringFlag{
 				Name:        "browser",
 				Aliases:     []string{"br"},
 				Value:       "chrome",
-				Usage:       "Default browser to use to run unit testing",
+				Usage:       "Comma separated list of browsers to use to run unit testing, each spec is run in every browser",
 				Destination: &cmd.Browser,
 			},
 			&cli.StringFlag{
@@ -75,23 +27,27 @@ func Cypress(c *cli.Context) (z *cli.Command) {
 				Usage:       "Relative path of cypress config if not cypress.config.js",
 				Destination: &cmd.ConfigFile,
 			},
-			&cli.BoolFlag{
-				Name:        "report-back",
-				Aliases:     []string{"rp"},
-				Usage:       "Send result to api",
-				Destination: &cmd.ReportBack,
+			&cli.StringFlag{
+				Name:        "config",
+				Aliases:     []string{"co"},
+				Value:       "",
+				Usage:       "Comma separated list of key=value overriding the cypress config file like baseUrl=http://localhost:8080",
+				Destination: &cmd.Config,
 			},
-			&cli.IntFlag{
-				Name:        "timeout",
-				Aliases:     []string{"t"},
-				Value:       10,
-				Usage:       "Timeout after which the program will exit with error",
-				Destination: &cmd.Timeout,
+			&cli.BoolFlag{
+				Name:        "component",
+				Aliases:     []string{"ct"},
+				Usage:       "Run component tests instead of end to end tests",
+				Destination: &cmd.Component,
 			},
-		},
+		),
 		Action: func(c *cli.Context) error {
-			cmd.Run()
-			return nil
+			return cmd.Run()
 		},
 	}
 }
+
+// ExitCode returns the exit code of the program matching the error
+func ExitCode(err error) int {
+	return cypress.ExitCode(err)
+}
@@ -8,38 +8,108 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/url"
 	"os"
-	"os/exec"
+	"os/signal"
+	"path"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/Lord-Y/cypress-parallel-cli/git"
+	"github.com/Lord-Y/cypress-parallel-cli/artifacts"
+	"github.com/Lord-Y/cypress-parallel-cli/cgroup"
+	"github.com/Lord-Y/cypress-parallel-cli/executor"
 	"github.com/Lord-Y/cypress-parallel-cli/httprequests"
+	"github.com/Lord-Y/cypress-parallel-cli/junit"
 	"github.com/Lord-Y/cypress-parallel-cli/logger"
-	"github.com/Lord-Y/golang-tools/tools"
-	"github.com/hashicorp/go-version"
+	"github.com/Lord-Y/cypress-parallel-cli/mochawesome"
+	"github.com/Lord-Y/cypress-parallel-cli/runner"
+	"github.com/Lord-Y/cypress-parallel-cli/specs"
+	"github.com/Lord-Y/cypress-parallel-cli/xvfb"
 	"github.com/rs/zerolog/log"
 )
 
 var apiURI = "/api/v1/executions/update"
 
+// Execution statuses sent back to the api
+const (
+	StatusDone      = "DONE"      // all tests passed
+	StatusFailed    = "FAILED"    // tests failed or could not be executed
+	StatusFlaky     = "FLAKY"     // tests passed after being retried
+	StatusTimeout   = "TIMEOUT"   // spec killed after reaching its timeout
+	StatusCancelled = "CANCELLED" // execution cancelled by a termination signal or other failures
+	StatusSkipped   = "SKIPPED"   // spec not run because of other failures
+)
+
+// memoryPerWorker is the memory needed by a worker to run cypress, the browser and Xvfb
+const memoryPerWorker = 1536 << 20
+
 // Cypress requirements to run cypress command
 type Cypress struct {
-	ApiURL     string // HTTP(s) api url of cypress-parallel-api
-	Repository string // HTTP(s) git repository
-	Username   string // Username to use to fetch repository if required
-	Password   string // Password to use to fetch repository if required
-	Branch     string // Branch in which specs are hold
-	Specs      string // Comma separated list of specs
-	UniqID     string // Uniq ID to run cypress command
-	Browser    string // Default browser to use to run unit testing
-	ConfigFile string // Relative path of cypress config if not cypress.config.js
-	ReportBack bool   // Notify api with cypress results
-	Timeout    int    // Timeout after which the program will exit with error
+	ApiURL        string            // HTTP(s) api url of cypress-parallel-api
+	Repository    string            // HTTP(s) git repository
+	Username      string            // Username to use to fetch repository if required
+	Password      string            // Password to use to fetch repository if required
+	Branch        string            // Branch in which specs are hold
+	Workdir       string            // Local directory of the project used instead of cloning Repository
+	InPlace       bool              // Run specs in Workdir instead of a scratch copy of it
+	ReportDir     string            // Directory in which the report of each spec is written, in the project when empty
+	Specs         string            // Comma separated list of specs
+	UniqID        string            // Uniq ID to run cypress command
+	Browser       string            // Comma separated list of browsers, each spec is run in every browser
+	ConfigFile    string            // Relative path of cypress config if not cypress.config.js
+	ReportBack    bool              // Notify api with cypress results
+	Timeout       int               // Timeout after which the program will exit with error
+	MaxParallel   int               // Maximum number of specs to run at the same time, 0 means computed from cgroup limits
+	Shard         string            // Portion of specs to run formatted as index/total like 2/5
+	Retries       int               // Number of times a failed spec is retried
+	Timings       string            // Path or HTTP(s) url of a json file with the last duration of each spec in milliseconds, used to balance shards
+	TimingsOutput string            // Path of the json file where the duration of each spec is written once the execution is done
+	Executor      executor.Executor // Executor used to run every command, executor.Local when nil
+	Runner        runner.Runner     // Framework used to run specs, runner.Cypress when nil
+	DryRun        bool              // Print the execution plan instead of running it
+	PlanFormat    string            // Format of the execution plan, text or json
+	SpecTimeout   time.Duration     // Maximum duration of each spec attempt, no limit when 0
+	Manifest      string            // Path of the json manifest overriding settings of some specs, relative to the repository unless absolute
+	GracePeriod   time.Duration     // Time given to processes to exit after SIGTERM before being killed on cancellation
+	FailFast      bool              // Stop at the first failed spec, same as MaxFailures set to 1
+	MaxFailures   int               // Number of failed specs after which running specs are cancelled and remaining ones skipped, 0 means never
+	Env           string            // Comma separated list of key=value variables exposed to specs
+	Config        string            // Comma separated list of key=value overriding the config file
+	Headed        bool              // Display the browser instead of running it headless
+	Component     bool              // Run component tests instead of end to end tests
+	Quiet         bool              // Only print the output of the reporter
+	JUnitOutput   string            // Path of the JUnit XML file written once the execution is done
+	HTMLReport    string            // Path of the merged html report written once the execution is done
+	DepsCacheDir  string            // Directory shared between executions in which installed dependencies are cached, disabled when empty
+
+	Artifacts          string // Where screenshots and videos of specs are uploaded, api or s3://bucket/prefix, disabled when empty
+	ArtifactsEndpoint  string // HTTP(s) url of the S3 compatible api like http://localhost:9000, AWS when empty
+	ArtifactsMaxSize   int    // Maximum size in megabytes of the artifacts uploaded for each spec and browser, 0 means unlimited
+	ArtifactsOnFailure bool   // Only upload artifacts of specs which are not DONE
+
+	specs     []string           // specs resolved from Specs once the repository is cloned
+	shard     *specs.Shard       // shard parsed from Shard
+	mu        sync.Mutex         // protect durations, stats, suites and pages
+	durations specs.Timings      // measured duration of each spec in milliseconds
+	stdout    io.Writer          // where the execution plan is printed, os.Stdout when nil
+	versions  runner.Version     // versions of the framework sent to the api
+	manifest  specs.Manifest     // manifest read from Manifest
+	env       map[string]string  // variables parsed from Env
+	overrides map[string]string  // config overrides parsed from Config
+	cancelled atomic.Bool        // set when a termination signal is received
+	uploader  artifacts.Uploader // uploader built from Artifacts, nil when disabled
+	stats     runner.Stats       // stats of the tests of every spec, protected by mu
+	suites    []junit.Testsuite  // JUnit testsuite of each reported unit, protected by mu
+	pages     []htmlSpec         // html report of each reported unit, protected by mu
+	depsCache depsCacheResult    // outcome of the dependencies cache lookup
+	timeout   time.Duration      // execution timeout overriding Timeout when set, used by tests
 }
 
 func init() {
@@ -47,403 +117,981 @@ func init() {
 }
 
 // Run will run cypress command
-func (c *Cypress) Run() {
-	var (
-		gc          git.Repository
-		zp          map[string]interface{}
-		npmPackages []string
-	)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.Timeout)*time.Minute)
+func (c *Cypress) Run() error {
+	if c.Shard != "" {
+		shard, err := specs.ParseShard(c.Shard)
+		if err != nil {
+			log.Error().Err(err).Msg("Error occured while parsing shard")
+			return err
+		}
+		c.shard = &shard
+	}
+	var err error
+	if c.env, err = parseValues(c.Env); err != nil {
+		log.Error().Err(err).Msg("Error occured while parsing env")
+		return err
+	}
+	if c.overrides, err = parseValues(c.Config); err != nil {
+		log.Error().Err(err).Msg("Error occured while parsing config")
+		return err
+	}
+	if c.PlanFormat != "" && c.PlanFormat != PlanFormatText && c.PlanFormat != PlanFormatJSON {
+		err := fmt.Errorf("unknown plan format %s", c.PlanFormat)
+		log.Error().Err(err).Msg("Error occured while parsing plan format")
+		return err
+	}
+	if c.Workdir, err = c.workdir(); err != nil {
+		log.Error().Err(err).Msg("Error occured while resolving workdir")
+		return err
+	}
+	if c.Repository == "" && c.Workdir == "" {
+		err = fmt.Errorf("repository or workdir is required")
+		log.Error().Err(err).Msg("Error occured while checking options")
+		return err
+	}
+	if c.ReportDir != "" {
+		if c.ReportDir, err = filepath.Abs(c.ReportDir); err != nil {
+			log.Error().Err(err).Msg("Error occured while resolving report dir")
+			return err
+		}
+	}
+	// paths are relative to the directory the cli is started from as the repository is removed once done
+	if c.Timings != "" && !strings.HasPrefix(c.Timings, "http://") && !strings.HasPrefix(c.Timings, "https://") {
+		if c.Timings, err = filepath.Abs(c.Timings); err != nil {
+			log.Error().Err(err).Msg("Error occured while resolving timings path")
+			return err
+		}
+	}
+	if c.TimingsOutput != "" {
+		if c.TimingsOutput, err = filepath.Abs(c.TimingsOutput); err != nil {
+			log.Error().Err(err).Msg("Error occured while resolving timings output path")
+			return err
+		}
+	}
+	if c.JUnitOutput != "" {
+		if c.JUnitOutput, err = filepath.Abs(c.JUnitOutput); err != nil {
+			log.Error().Err(err).Msg("Error occured while resolving JUnit output path")
+			return err
+		}
+		defer c.writeJUnit()
+	}
+	if c.HTMLReport != "" {
+		if c.HTMLReport, err = filepath.Abs(c.HTMLReport); err != nil {
+			log.Error().Err(err).Msg("Error occured while resolving html report path")
+			return err
+		}
+		defer c.writeHTML()
+	}
+	if c.Artifacts != "" && c.uploader == nil {
+		if c.uploader, err = artifacts.NewUploader(c.Artifacts, c.ApiURL, c.ArtifactsEndpoint); err != nil {
+			log.Error().Err(err).Msg("Error occured while configuring artifacts upload")
+			return err
+		}
+	}
+
+	executionTimeout := time.Duration(c.Timeout) * time.Minute
+	if c.timeout > 0 {
+		executionTimeout = c.timeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), executionTimeout)
 	defer cancel()
 
-	gc.Repository = c.Repository
-	gc.Username = c.Username
-	gc.Password = c.Password
-	gc.Ref = c.Branch
+	// running processes are given GracePeriod to exit once the context is cancelled
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(sigc)
+	go func() {
+		select {
+		case sig := <-sigc:
+			log.Warn().Msgf("Signal %s received, cancelling execution, send it again to exit immediately", sig)
+			c.cancelled.Store(true)
+			// a second signal gets the default behaviour and terminates the process right away
+			signal.Stop(sigc)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
 
-	gitdir, err := gc.Clone()
+	gitdir, cleanup, err := c.checkout()
 	if err != nil {
 		c.reportBack(err, "", true, "{}", false)
-		log.Error().Err(err).Msg("Error occured while cloning git repository")
-		return
+		return newRunError(ctx, ExitInfrastructure, err)
 	}
-	defer os.RemoveAll(gitdir)
-	log.Debug().Msgf("Git temp dir %s", gitdir)
+	defer cleanup()
+	log.Debug().Msgf("Project dir %s", gitdir)
 
 	if ctx.Err() == context.DeadlineExceeded {
-		c.reportBack(err, "", true, "{}", false)
+		c.reportBack(ctx.Err(), "", true, "{}", false)
 		log.Error().Err(ctx.Err()).Msgf("Execution timeout reached after %d minute(s)", c.Timeout)
-		return
+		return newRunError(ctx, ExitTimeout, ctx.Err())
 	}
-
-	err = os.Chdir(gitdir)
-	if err != nil {
-		c.reportBack(err, "", true, "{}", false)
-		log.Error().Err(err).Msg("Error occured while chdir git repository")
-		return
+	if c.cancelled.Load() {
+		c.reportBack(ctx.Err(), "", true, "{}", false)
+		log.Error().Err(ctx.Err()).Msg("Execution has been cancelled")
+		return newRunError(ctx, ExitCancelled, ctx.Err())
 	}
 
 	if c.ConfigFile != "" {
 		var info os.FileInfo
-		if info, err = os.Stat(fmt.Sprintf("%s/%s", gitdir, c.ConfigFile)); os.IsNotExist(err) {
+		if info, err = os.Stat(filepath.Join(gitdir, c.ConfigFile)); err != nil {
 			c.reportBack(err, "", true, "{}", false)
 			log.Error().Err(err).Msgf("Error occured while checking config file %s", c.ConfigFile)
-			return
+			return newRunError(ctx, ExitInfrastructure, err)
 		}
 		if !info.Mode().IsRegular() {
+			err = fmt.Errorf("config file %s is not a regular file", c.ConfigFile)
 			c.reportBack(err, "", true, "{}", false)
 			log.Error().Err(err).Msgf("Error occured while checking config file %s", c.ConfigFile)
-			return
+			return newRunError(ctx, ExitInfrastructure, err)
+		}
+	}
+
+	if c.Manifest != "" {
+		manifest := c.Manifest
+		if !filepath.IsAbs(manifest) {
+			manifest = filepath.Join(gitdir, manifest)
+		}
+		if c.manifest, err = specs.ReadManifest(manifest); err != nil {
+			c.reportBack(err, "", true, "{}", false)
+			log.Error().Err(err).Msgf("Error occured while reading manifest %s", c.Manifest)
+			return newRunError(ctx, ExitInfrastructure, err)
 		}
 	}
 
-	c1 := exec.Command("cypress", "--version")
-	c2 := exec.Command("grep", `Cypress package version: `)
-	c2.Stdin, err = c1.StdoutPipe()
+	resolved, err := specs.Expand(gitdir, specs.SplitPatterns(c.Specs), c.runner().SpecNames())
 	if err != nil {
 		c.reportBack(err, "", true, "{}", false)
-		log.Error().Err(err).Msg("Error occured while getting stdout pipe")
-		return
+		log.Error().Err(err).Msgf("Error occured while resolving specs %s", c.Specs)
+		return newRunError(ctx, ExitInfrastructure, err)
 	}
+	if len(resolved) == 0 {
+		err = fmt.Errorf("no spec found matching %s", c.Specs)
+		c.reportBack(err, "", true, "{}", false)
+		log.Error().Err(err).Msg("Error occured while resolving specs")
+		return newRunError(ctx, ExitInfrastructure, err)
+	}
+	log.Debug().Msgf("Resolved specs %s", strings.Join(resolved, ","))
 
-	var outputCypressVersion bytes.Buffer
-	c2.Stdout = &outputCypressVersion
-	err = c2.Start()
-	if err != nil {
+	if c.shard != nil {
+		if c.Timings != "" {
+			timings, err := specs.ReadTimings(c.Timings)
+			if err != nil {
+				c.reportBack(err, "", true, "{}", false)
+				log.Error().Err(err).Msgf("Error occured while reading timings from %s", c.Timings)
+				return newRunError(ctx, ExitInfrastructure, err)
+			}
+			resolved = c.shard.Balance(resolved, timings)
+		} else {
+			resolved = c.shard.Split(resolved)
+		}
+		if len(resolved) == 0 {
+			log.Info().Msgf("No spec assigned to shard %s, nothing to do", c.shard)
+			return nil
+		}
+		log.Debug().Msgf("Specs assigned to shard %s %s", c.shard, strings.Join(resolved, ","))
+	}
+	c.specs = resolved
+
+	if c.DryRun {
+		plan, err := c.plan(ctx, gitdir)
+		if err != nil {
+			log.Error().Err(err).Msg("Error occured while planning execution")
+			return newRunError(ctx, ExitInfrastructure, err)
+		}
+		return plan.Print(c.output(), c.PlanFormat)
+	}
+
+	framework := c.runner()
+	if err = c.install(ctx, framework, gitdir); err != nil {
 		c.reportBack(err, "", true, "{}", false)
-		log.Error().Err(err).Msg("Error occured while starting cypress version command")
-		return
+		return newRunError(ctx, ExitInfrastructure, err)
 	}
-	err = c1.Run()
+
+	c.versions, err = framework.Version(ctx, c.executor(), gitdir)
 	if err != nil {
 		c.reportBack(err, "", true, "{}", false)
-		log.Error().Err(err).Msg("Error occured while running cypress version command")
-		return
+		return newRunError(ctx, ExitInfrastructure, err)
 	}
-	err = c2.Wait()
-	if err != nil {
+	log.Debug().Msgf("%s version %s", framework.Name(), c.versions)
+
+	if err = c.checkBrowsers(ctx, gitdir); err != nil {
 		c.reportBack(err, "", true, "{}", false)
-		log.Error().Err(err).Msg("Error occured while waiting cypress version command")
-		return
+		log.Error().Err(err).Msg("Error occured while checking browsers")
+		return newRunError(ctx, ExitInfrastructure, err)
+	}
+
+	units := c.units()
+	workers := c.maxParallel(len(units))
+	log.Debug().Msgf("Running %d spec(s) in %d browser(s) with %d worker(s)", len(c.specs), len(c.browsers()), workers)
+
+	var (
+		mu       sync.Mutex
+		code     int
+		failures int
+	)
+	// specs are run with a context cancelled once the maximum number of failures is reached
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	maxFailures := c.maxFailures()
+
+	// https://docs.cypress.io/guides/continuous-integration/introduction#Xvfb
+	displays := xvfb.NewManager()
+	displays.Executor = c.executor()
+	defer displays.Close()
+	go func() {
+		<-ctx.Done()
+		// cancelled specs keep their display during the grace period to write their report
+		if ctx.Err() == context.DeadlineExceeded {
+			displays.Close()
+		}
+	}()
+	useXvfb := c.display(gitdir)
+	if !useXvfb {
+		log.Debug().Msg("DISPLAY is already set or browser runs headless, Xvfb will not be started")
+	}
+
+	queue := make(chan unit)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			var display *xvfb.Server
+			if useXvfb {
+				// a server failing to start is started again by runSpec before each unit
+				var err error
+				if display, err = displays.Start(ctx); err != nil {
+					log.Warn().Err(err).Msg("Error occured while starting Xvfb, it will be started again before running specs")
+				}
+			}
+
+			for u := range queue {
+				specCode := ExitInfrastructure
+				if c.cancelled.Load() {
+					specCode = ExitCancelled
+					c.report(context.Canceled, u.spec, specReport{browser: u.browser, status: StatusCancelled, result: "{}"})
+				} else if runCtx.Err() == context.DeadlineExceeded {
+					// specs not started before the execution timeout are not run
+					specCode = ExitTimeout
+					c.report(runCtx.Err(), u.spec, specReport{browser: u.browser, status: StatusTimeout, result: "{}"})
+				} else if runCtx.Err() == context.Canceled {
+					specCode = ExitSuccess
+					c.report(nil, u.spec, specReport{browser: u.browser, status: StatusSkipped, result: "{}"})
+				} else {
+					specCode = c.runSpec(runCtx, gitdir, c.versions.String(), display, u)
+					if specCode == ExitCancelled && !c.cancelled.Load() {
+						// cancelled because of other failures
+						specCode = ExitSuccess
+					}
+				}
+				mu.Lock()
+				code = worse(code, specCode)
+				if specCode != ExitSuccess && specCode != ExitCancelled {
+					failures++
+					if maxFailures > 0 && failures == maxFailures {
+						log.Error().Msgf("Maximum number of failures %d reached, cancelling running specs and skipping remaining ones", maxFailures)
+						stop()
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, u := range units {
+		queue <- u
+	}
+	close(queue)
+	wg.Wait()
+	c.writeTimings()
+	if c.stats != (runner.Stats{}) {
+		log.Info().Msgf("Tests: %d, passed %d, failed %d, pending %d, skipped %d", c.stats.Tests, c.stats.Passes, c.stats.Failures, c.stats.Pending, c.stats.Skipped)
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Error().Err(ctx.Err()).Msgf("Execution timeout reached after %d minute(s)", c.Timeout)
+		return newRunError(ctx, ExitTimeout, ctx.Err())
+	}
+	if c.cancelled.Load() {
+		log.Error().Msg("Execution has been cancelled")
+		return newRunError(ctx, ExitCancelled, context.Canceled)
+	}
+	if code != ExitSuccess {
+		log.Error().Msgf("Program execution failed with exit code %d", code)
+		return newRunError(ctx, code, fmt.Errorf("execution of one or more specs failed"))
+	}
+	log.Info().Msg("Program execution successful")
+	return nil
+}
+
+// maxParallel returns the number of workers to use to run specs
+func (c *Cypress) maxParallel(specs int) (z int) {
+	z = c.MaxParallel
+	if z <= 0 {
+		z = defaultMaxParallel()
+	}
+	if z > specs {
+		z = specs
+	}
+	if z < 1 {
+		z = 1
+	}
+	return
+}
+
+// maxFailures returns the number of failed specs stopping the execution, 0 when disabled
+func (c *Cypress) maxFailures() int {
+	if c.MaxFailures > 0 {
+		return c.MaxFailures
+	}
+	if c.FailFast {
+		return 1
+	}
+	return 0
+}
+
+// defaultMaxParallel returns the number of workers allowed by cgroup limits
+func defaultMaxParallel() int {
+	return workers(runtime.NumCPU(), cgroup.CPU(), cgroup.Memory())
+}
+
+// workers returns the number of workers allowed on cpus by the cpu and memory limits, which are 0 when not set
+func workers(cpus int, cpu float64, memory int64) (z int) {
+	z = cpus
+	// fractional quotas like 500m still allow one worker
+	if limit := int(math.Max(cpu, 1)); cpu > 0 && limit < z {
+		z = limit
+	}
+	if limit := int(memory / memoryPerWorker); memory > 0 && limit < z {
+		z = limit
+	}
+	if z < 1 {
+		z = 1
+	}
+	return
+}
+
+// runSpec runs the framework for the given unit on the worker display and report back the result.
+// Failed attempts are retried in a fresh process and display up to Retries times.
+// display is nil when Xvfb is not required.
+// It returns the exit code matching the spec execution
+func (c *Cypress) runSpec(ctx context.Context, gitdir string, frameworkVersion string, display *xvfb.Server, u unit) int {
+	var (
+		a       attempt
+		results [][]byte
+		reports []string
+	)
+	start := time.Now()
+	for n := 1; n <= c.Retries+1; n++ {
+		var screen string
+		if display != nil {
+			var err error
+			if n > 1 {
+				err = display.Restart(ctx)
+			} else {
+				err = display.Ensure(ctx)
+			}
+			if err != nil {
+				log.Error().Err(err).Msgf("Fail to execute Xvfb command %s", err.Error())
+				a = attempt{code: ExitInfrastructure, err: err}
+				break
+			}
+			screen = display.Display
+		}
+		if n > 1 {
+			log.Warn().Msgf("Retrying spec %s, attempt %d/%d", u, n, c.Retries+1)
+		}
+		a = c.runAttempt(ctx, gitdir, frameworkVersion, screen, u, n)
+		results = append(results, a.result)
+		reports = append(reports, c.attemptReport(u, n))
+		if a.code == ExitSuccess || ctx.Err() != nil {
+			break
+		}
+	}
+	duration := time.Since(start)
+	// the duration of a cancelled spec would unbalance next shards
+	if a.code != ExitCancelled {
+		c.recordDuration(u.spec, duration)
+	}
+
+	r := specReport{
+		browser:  u.browser,
+		status:   StatusDone,
+		result:   "{}",
+		duration: duration.Milliseconds(),
+	}
+	if a.result != nil {
+		r.content = a.result
+		r.result = hex.EncodeToString(a.result)
+		r.encoded = true
+	}
+	if c.Retries > 0 {
+		r.attempts = results
+	}
+	if a.stats != nil {
+		r.stats = a.stats
+		r.tests = a.tests
+		c.logTests(u, *a.stats, a.tests)
+	}
+	if a.code == ExitTimeout {
+		r.status = StatusTimeout
+	} else if a.code == ExitCancelled {
+		r.status = StatusCancelled
+	} else if a.code != ExitSuccess {
+		r.status = StatusFailed
+	} else if len(results) > 1 {
+		r.status = StatusFlaky
+		log.Warn().Msgf("Spec %s is flaky, it passed after %d attempts", u, len(results))
+	}
+	// uploads would not fit in the grace period of cancelled executions
+	if c.uploader != nil && !c.cancelled.Load() && (r.status != StatusDone || !c.ArtifactsOnFailure) {
+		r.artifacts = c.uploadArtifacts(ctx, gitdir, u, reports)
 	}
+	c.report(a.err, u.spec, r)
+	return a.code
+}
+
+// logTests logs the stats of the unit, the failed tests and adds the stats to the ones of the execution
+func (c *Cypress) logTests(u unit, stats runner.Stats, tests []runner.Test) {
+	c.mu.Lock()
+	c.stats.Add(stats)
+	c.mu.Unlock()
+
+	log.Info().Msgf("Spec %s: %d test(s), %d passed, %d failed, %d pending, %d skipped", u, stats.Tests, stats.Passes, stats.Failures, stats.Pending, stats.Skipped)
+	for _, test := range tests {
+		if test.State == mochawesome.Failed {
+			log.Error().Msgf("Test %s of spec %s failed after %dms: %s", test.Title, u, test.Duration, test.Error)
+		}
+	}
+}
+
+// attempt is the outcome of a single execution of a spec
+type attempt struct {
+	code   int           // exit code matching the execution
+	err    error         // error preventing the execution, nil when tests have been executed
+	result []byte        // compacted mochawesome result, nil when not available
+	stats  *runner.Stats // stats of the tests, nil when not available
+	tests  []runner.Test // outcome of each test
+}
 
-	log.Debug().Msgf("Cypress version output %s", strings.TrimSpace(outputCypressVersion.String()))
-	outputSplit := strings.Split(strings.TrimSpace(outputCypressVersion.String()), " ")
-	cypressVersion := outputSplit[len(outputSplit)-1]
-	log.Debug().Msgf("Cypress version %s", cypressVersion)
+// runAttempt runs the framework once for the given unit on the given screen
+func (c *Cypress) runAttempt(ctx context.Context, gitdir string, frameworkVersion string, screen string, u unit, n int) attempt {
+	reportFilename := c.attemptReport(u, n)
 
-	packages, err := os.ReadFile(gitdir + "/package.json")
+	framework := c.runner()
+	x := c.execution(gitdir, frameworkVersion, u, reportFilename)
+	process, err := framework.Command(x)
 	if err != nil {
-		c.reportBack(err, "", true, "{}", false)
-		log.Error().Err(err).Msg("Error occured while getting package.json file")
-		return
+		return attempt{code: ExitInfrastructure, err: err}
+	}
+	if screen != "" {
+		process.Env = append(process.Env, fmt.Sprintf("DISPLAY=%s", screen))
+	}
+	// workdirs and report dirs may keep files of previous runs which would be taken for the ones of the attempt
+	for _, stale := range []string{framework.ResultFile(x), x.Artifacts} {
+		if stale == "" {
+			continue
+		}
+		if err = os.RemoveAll(stale); err != nil {
+			log.Error().Err(err).Msgf("Error occured while removing %s", stale)
+			return attempt{code: ExitInfrastructure, err: err}
+		}
+	}
+	log.Debug().Msgf("Running %s command %s", framework.Name(), process)
+
+	// the process group of the spec is killed when its timeout is reached
+	specCtx := ctx
+	timeout := c.specTimeout(u.spec)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		specCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
-	err = json.Unmarshal(packages, &zp)
+	var execution_failed bool
+	output, err := executor.Output(specCtx, c.executor(), process)
+	log.Debug().Msgf("Execution output %s", string(output))
 	if err != nil {
-		c.reportBack(err, "", true, "{}", false)
-		log.Error().Err(err).Msgf("Error occured while unmarshalling package.json")
-		return
+		log.Error().Err(err).Msgf("Fail to execute %s command %s", framework.Name(), string(output))
+		execution_failed = true
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Error().Err(ctx.Err()).Msgf("Execution timeout reached after %d minute(s)", c.Timeout)
+		return attempt{code: ExitTimeout, err: ctx.Err()}
+	}
+	if ctx.Err() == context.Canceled {
+		log.Warn().Msgf("Spec %s has been cancelled", u)
+		// keep whatever the reporter wrote before the process exited
+		result, _ := c.readResult(framework, x)
+		return attempt{code: ExitCancelled, err: context.Canceled, result: result.Content}
+	}
+	if specCtx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("spec timeout reached after %s", timeout)
+		log.Error().Err(err).Msgf("Spec %s has been killed", u)
+		return attempt{code: ExitTimeout, err: err}
+	}
+
+	parsed, err := c.readResult(framework, x)
+	if err != nil {
+		return attempt{code: ExitInfrastructure, err: err}
 	}
 
-	if zp["dependencies"] != nil {
-		m, err := tools.ConvertMapStringInterfaceToMapStringString(zp["dependencies"].(map[string]interface{}))
+	a := attempt{code: ExitSuccess, result: parsed.Content, stats: parsed.Stats, tests: parsed.Tests}
+	if parsed.Stats == nil {
+		if execution_failed {
+			a.code = ExitTestFailure
+		}
+		return a
+	}
+	// test results decide the outcome as the process may fail after all tests passed
+	if parsed.Stats.Failures > 0 || execution_failed && parsed.Stats.Tests == 0 {
+		a.code = ExitTestFailure
+	} else if execution_failed {
+		log.Warn().Msgf("%s command of spec %s failed but all its tests passed", framework.Name(), u)
+	}
+	return a
+}
+
+// output returns where the execution plan is printed
+func (c *Cypress) output() io.Writer {
+	if c.stdout == nil {
+		return os.Stdout
+	}
+	return c.stdout
+}
+
+// runner returns the framework used to run specs
+func (c *Cypress) runner() runner.Runner {
+	if c.Runner == nil {
+		return runner.Cypress{}
+	}
+	return c.Runner
+}
+
+// execution returns the execution of the unit with the settings of the manifest
+func (c *Cypress) execution(gitdir string, frameworkVersion string, u unit, reportFilename string) runner.Execution {
+	settings := c.manifest.Settings(u.spec)
+	z := runner.Execution{
+		Dir:       gitdir,
+		Spec:      u.spec,
+		Browser:   u.browser,
+		Config:    c.ConfigFile,
+		Version:   frameworkVersion,
+		Report:    reportFilename,
+		ReportDir: c.ReportDir,
+		Env:       merge(c.env, settings.Env),
+		Overrides: merge(c.overrides, settings.Config),
+		Headed:    c.Headed,
+		Component: c.Component,
+		Quiet:     c.Quiet,
+	}
+	if settings.Headed != nil {
+		z.Headed = *settings.Headed
+	}
+	if settings.Component != nil {
+		z.Component = *settings.Component
+	}
+	if settings.Quiet != nil {
+		z.Quiet = *settings.Quiet
+	}
+	if c.uploader != nil {
+		z.Artifacts = c.artifactsDir(gitdir, reportFilename)
+	}
+	return z
+}
+
+// artifactsDir returns the directory in which the attempt writes its screenshots and videos
+func (c *Cypress) artifactsDir(gitdir string, report string) string {
+	return filepath.Join(gitdir, ".artifacts", report)
+}
+
+// uploadArtifacts uploads the screenshots and videos of every attempt of the unit
+// within ArtifactsMaxSize and returns their urls
+func (c *Cypress) uploadArtifacts(ctx context.Context, gitdir string, u unit, reports []string) []string {
+	var files []artifacts.File
+	for _, report := range reports {
+		collected, err := artifacts.Collect(c.artifactsDir(gitdir, report))
 		if err != nil {
-			c.reportBack(err, "", true, "{}", false)
-			log.Error().Err(err).Msgf("Error occured while converting dependencies")
-			return
+			log.Error().Err(err).Msgf("Error occured while collecting artifacts of spec %s", u)
+			continue
 		}
-		for k := range m {
-			if strings.Contains(k, "cypress") {
-				npmPackages = append(npmPackages, k)
-			}
+		for _, f := range collected {
+			f.Key = path.Join(report, f.Key)
+			files = append(files, f)
 		}
 	}
+	metadata := map[string]string{
+		"uniqId":  c.UniqID,
+		"branch":  c.Branch,
+		"spec":    u.spec,
+		"browser": u.browser,
+	}
+	z := artifacts.Upload(ctx, c.uploader, c.UniqID, files, metadata, int64(c.ArtifactsMaxSize)<<20)
+	log.Debug().Msgf("%d artifact(s) of spec %s uploaded", len(z), u)
+	return z
+}
 
-	if zp["devDependencies"] != nil {
-		m, err := tools.ConvertMapStringInterfaceToMapStringString(zp["devDependencies"].(map[string]interface{}))
-		if err != nil {
-			c.reportBack(err, "", true, "{}", false)
-			log.Error().Err(err).Msgf("Error occured while converting devDependencies")
-			return
+// display returns true when at least one unit needs an X display
+func (c *Cypress) display(gitdir string) bool {
+	for _, u := range c.units() {
+		if c.runner().Display(c.execution(gitdir, c.versions.String(), u, c.reportFilename(u))) {
+			return true
 		}
-		for k := range m {
-			if strings.Contains(k, "cypress") {
-				npmPackages = append(npmPackages, k)
+	}
+	return false
+}
+
+// merge returns the values of a overridden by the ones of b, nil when both are empty
+func merge(a map[string]string, b map[string]string) (z map[string]string) {
+	for _, values := range []map[string]string{a, b} {
+		for key, value := range values {
+			if z == nil {
+				z = make(map[string]string)
 			}
+			z[key] = value
 		}
 	}
+	return
+}
 
-	execUninstallCmd := exec.CommandContext(
-		ctx,
-		"npm",
-		"uninstall",
-		strings.Join(npmPackages, " "),
-	)
-	log.Debug().Msgf("Uninstall cypress packages: %s", strings.Join(npmPackages, " "))
-
-	if err := execUninstallCmd.Run(); err != nil {
-		c.reportBack(err, "", true, "{}", false)
-		log.Error().Err(err).Msg("Error occured while forcing uninstall of local cypress package")
+// parseValues parses values formatted like key1=value1,key2=value2
+func parseValues(s string) (z map[string]string, err error) {
+	if s == "" {
 		return
 	}
+	z = make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid value %s, it must be formatted like key=value", pair)
+		}
+		z[strings.TrimSpace(key)] = value
+	}
+	return
+}
 
-	output, err := exec.CommandContext(
-		ctx,
-		"npm",
-		"install",
-	).Output()
-	log.Debug().Msgf("NPM user packages install output %s", string(output))
+// readResult returns the parsed result written by the framework
+func (c *Cypress) readResult(framework runner.Runner, x runner.Execution) (runner.Result, error) {
+	result := framework.ResultFile(x)
+	of, err := os.Open(result)
+	if err != nil {
+		log.Error().Err(err).Msgf("Fail to open file %s", result)
+		return runner.Result{}, err
+	}
+	defer of.Close()
+	fo, err := io.ReadAll(of)
+	if err != nil {
+		log.Error().Err(err).Msgf("Fail to read file %s content", result)
+		return runner.Result{}, err
+	}
 
+	parsed, err := framework.ParseResult(fo)
 	if err != nil {
-		c.reportBack(err, "", true, "{}", false)
-		log.Error().Err(err).Msgf("Error occured while installing user packages")
-		return
+		log.Error().Err(err).Msg("Fail to parse result")
+		return runner.Result{}, err
 	}
+	return parsed, nil
+}
 
-	output, err = exec.CommandContext(
-		ctx,
-		"npm",
-		"install",
-		"--save-dev",
-		"mochawesome",
-	).Output()
-	log.Debug().Msgf("Mochawesome install output %s", string(output))
+// specTimeout returns the maximum duration of an attempt of the spec, 0 when unlimited
+func (c *Cypress) specTimeout(spec string) time.Duration {
+	if timeout := c.manifest.Settings(spec).Timeout; timeout != 0 {
+		return time.Duration(timeout)
+	}
+	return c.SpecTimeout
+}
 
-	if err != nil {
-		log.Error().Err(err).Msgf("Error occured while installing mochawesome")
-		c.reportBack(err, "", true, "{}", false)
+// executor returns the executor used to run commands
+func (c *Cypress) executor() executor.Executor {
+	if c.Executor == nil {
+		return executor.Local{GracePeriod: c.GracePeriod}
+	}
+	return c.Executor
+}
+
+// recordDuration adds the measured wall-clock duration of the spec in a browser
+// to the ones measured in other browsers
+func (c *Cypress) recordDuration(spec string, duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.durations == nil {
+		c.durations = make(specs.Timings)
+	}
+	c.durations[spec] += duration.Milliseconds()
+	log.Debug().Msgf("Spec %s executed in %s", spec, duration)
+}
+
+// duration returns the measured duration of the spec in milliseconds
+func (c *Cypress) duration(spec string) (z int64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	z, ok = c.durations[spec]
+	return
+}
+
+// writeTimings updates TimingsOutput file with measured durations
+func (c *Cypress) writeTimings() {
+	if c.TimingsOutput == "" {
 		return
 	}
+	timings, err := specs.ReadTimings(c.TimingsOutput)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Error occured while reading timings from %s, they will be overridden", c.TimingsOutput)
+		timings = make(specs.Timings)
+	}
 
-	specs := strings.Split(c.Specs, ",")
-	wg := sync.WaitGroup{}
-	for i, spec := range specs {
-		wg.Add(1)
-		go func(i int, spec string) {
-			defer wg.Done()
-			// https://docs.cypress.io/guides/continuous-integration/introduction#Xvfb
-			screen := fmt.Sprintf(":%d", 99+i)
-			cmd := exec.Command("sh", "-c", fmt.Sprintf("Xvfb %s &", screen))
-			log.Debug().Msgf("Execution output of screen command %s", cmd.String())
-			err := cmd.Run()
-			if err != nil {
-				c.reportBack(err, spec, true, "{}", false)
-				log.Error().Err(err).Msgf("Fail to execute Xvfb command %s", err.Error())
-				return
-			}
+	c.mu.Lock()
+	for spec, duration := range c.durations {
+		timings[spec] = duration
+	}
+	c.mu.Unlock()
 
-			f := filepath.Base(spec)
-			reportFilename := strings.TrimSuffix(f, ".spec.js")
-			reportFilename = strings.TrimSuffix(reportFilename, ".cy.js")
+	if err := timings.Write(c.TimingsOutput); err != nil {
+		log.Error().Err(err).Msgf("Error occured while writing timings to %s", c.TimingsOutput)
+	}
+}
 
-			v1, err := version.NewVersion(cypressVersion)
-			if err != nil {
-				c.reportBack(err, spec, true, "{}", false)
-				log.Error().Err(err).Msgf("Error occured while initializing cypress version v1")
-				return
-			}
-			v2, err := version.NewVersion("10.0.0")
-			if err != nil {
-				c.reportBack(err, spec, true, "{}", false)
-				log.Error().Err(err).Msgf("Error occured while initializing cypress version v2")
-				return
-			}
+// specList returns the resolved specs or the patterns provided by the user when they are not resolved yet.
+// Patterns are reported once per shard as the specs of the shard are only known once resolved
+func (c *Cypress) specList() []string {
+	if c.specs != nil {
+		return c.specs
+	}
+	if c.shard != nil {
+		return []string{c.Specs}
+	}
+	return specs.SplitPatterns(c.Specs)
+}
 
-			var (
-				args    []string
-				process *exec.Cmd
-			)
-
-			if v1.LessThan(v2) {
-				args = []string{
-					"run",
-					"--browser",
-					c.Browser,
-					"--headless",
-					"--spec",
-					spec,
-					"--reporter",
-					"mochawesome",
-					"--reporter-options",
-					fmt.Sprintf("reportFilename=%s", reportFilename),
-				}
+// unit is a spec run in a browser, the unit of work of workers
+type unit struct {
+	spec    string
+	browser string
+}
 
-				process = exec.CommandContext(
-					ctx,
-					"cypress",
-					"run",
-					"--browser",
-					c.Browser,
-					"--headless",
-					"--spec",
-					spec,
-					"--reporter",
-					"mochawesome",
-					"--reporter-options",
-					fmt.Sprintf("reportFilename=%s", reportFilename),
-				)
-			} else {
-				args = []string{
-					"run",
-					"--browser",
-					c.Browser,
-					"--spec",
-					spec,
-					"--reporter",
-					"mochawesome",
-					"--reporter-options",
-					fmt.Sprintf("reportFilename=%s", reportFilename),
-				}
+// String returns the spec followed by the browser when set
+func (u unit) String() string {
+	if u.browser == "" {
+		return u.spec
+	}
+	return fmt.Sprintf("%s in %s", u.spec, u.browser)
+}
 
-				process = exec.CommandContext(
-					ctx,
-					"cypress",
-					"run",
-					"--browser",
-					c.Browser,
-					"--spec",
-					spec,
-					"--reporter",
-					"mochawesome",
-					"--reporter-options",
-					fmt.Sprintf("reportFilename=%s", reportFilename),
-				)
-			}
-			log.Debug().Msgf("Running cypress command %s %s", "cypress", strings.Join(args, " "))
-
-			process.Env = append(
-				os.Environ(),
-				fmt.Sprintf("DISPLAY=%s", screen),
-				fmt.Sprintf("NO_COLOR=%d", 1),
-			)
-			process.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-			go func() {
-				<-ctx.Done()
-				if ctx.Err() == context.DeadlineExceeded {
-					_ = syscall.Kill(-process.Process.Pid, syscall.SIGKILL)
-					c.reportBack(ctx.Err(), spec, true, "{}", false)
-					log.Error().Err(ctx.Err()).Msgf("Execution timeout reached after %d minute(s)", c.Timeout)
-					return
-				}
-			}()
-			var execution_failed bool
-			output, err := process.Output()
-			log.Debug().Msgf("Execution output %s", string(output))
-			if err != nil {
-				log.Error().Err(err).Msgf("Fail to execute cypress command %s", string(output))
-				execution_failed = true
-			}
+// browsers returns the browsers parsed from Browser without duplicates.
+// It returns a single empty browser when Browser is empty so the framework default is used
+func (c *Cypress) browsers() (z []string) {
+	seen := make(map[string]bool)
+	for _, browser := range strings.Split(c.Browser, ",") {
+		browser = strings.TrimSpace(browser)
+		if browser != "" && !seen[browser] {
+			seen[browser] = true
+			z = append(z, browser)
+		}
+	}
+	if len(z) == 0 {
+		z = []string{""}
+	}
+	return
+}
 
-			result := fmt.Sprintf("%s/mochawesome-report/%s.json", gitdir, reportFilename)
-			of, err := os.Open(result)
-			if err != nil {
-				c.reportBack(err, spec, true, "{}", false)
-				log.Error().Err(err).Msgf("Fail to open file %s", result)
-				return
-			}
-			defer of.Close()
-			fo, err := io.ReadAll(of)
-			if err != nil {
-				c.reportBack(err, spec, true, "{}", false)
-				log.Error().Err(err).Msgf("Fail to read file %s content", result)
-				return
-			}
+// units returns every resolved spec in every browser
+func (c *Cypress) units() []unit {
+	return c.unitsOf(c.specs, c.browsers())
+}
+
+// unitsOf returns every spec in every browser, browsers of a spec are next to each other
+func (c *Cypress) unitsOf(specs []string, browsers []string) (z []unit) {
+	for _, spec := range specs {
+		for _, browser := range browsers {
+			z = append(z, unit{spec: spec, browser: browser})
+		}
+	}
+	return
+}
+
+// checkBrowsers returns an error listing the browsers which are not installed.
+// Browsers given by path and frameworks unable to list their browsers are not checked
+func (c *Cypress) checkBrowsers(ctx context.Context, gitdir string) error {
+	framework := c.runner()
+	installed, err := framework.Browsers(ctx, c.executor(), gitdir)
+	if err != nil {
+		return err
+	}
+	if installed == nil {
+		return nil
+	}
+	available := make(map[string]bool)
+	for _, browser := range installed {
+		available[browser] = true
+	}
+	var missing []string
+	for _, browser := range c.browsers() {
+		if browser != "" && !strings.Contains(browser, "/") && !available[browser] {
+			missing = append(missing, browser)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("browser(s) %s not installed, %s detected %s", strings.Join(missing, ","), framework.Name(), strings.Join(installed, ","))
+	}
+	return nil
+}
 
-			buf := new(bytes.Buffer)
-			if err := json.Compact(buf, fo); err != nil {
-				c.reportBack(err, spec, true, "{}", false)
-				log.Error().Err(err).Msg("Fail to compact json result")
-				return
+// reportFilename returns the report filename of the unit.
+// The browser is part of the name when specs are run in several browsers
+func (c *Cypress) reportFilename(u unit) string {
+	z := reportFilename(u.spec)
+	if len(c.browsers()) > 1 {
+		z = fmt.Sprintf("%s_%s", z, strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+				return r
 			}
+			return '_'
+		}, strings.Trim(u.browser, "/")))
+	}
+	return z
+}
 
-			c.reportBack(err, spec, execution_failed, hex.EncodeToString(buf.Bytes()), true)
-		}(i, spec)
+// attemptReport returns the report filename of the nth attempt of the unit
+func (c *Cypress) attemptReport(u unit, n int) string {
+	z := c.reportFilename(u)
+	if n > 1 {
+		z = fmt.Sprintf("%s_attempt%d", z, n)
 	}
-	wg.Wait()
-	log.Info().Msg("Program execution successful")
+	return z
+}
+
+// reportFilename returns the mochawesome report filename of the spec.
+// The spec directory is part of the name so specs with the same name
+// in different directories do not override each other
+func reportFilename(spec string) string {
+	z := strings.TrimSuffix(spec, path.Ext(spec))
+	z = strings.TrimSuffix(z, ".spec")
+	z = strings.TrimSuffix(z, ".cy")
+	return strings.ReplaceAll(strings.Trim(z, "./"), "/", "_")
 }
 
+// reportBack sends the result of the spec with DONE or FAILED status.
+// When spec is empty, the result is sent for all specs
 func (c *Cypress) reportBack(err error, spec string, executionFailed bool, result string, encoded bool) {
-	if c.ReportBack {
-		headers := make(map[string]string)
-		headers["Content-Type"] = "application/x-www-form-urlencoded"
-		if spec != "" {
-			payload := url.Values{}
-			payload.Set("result", result)
-			if executionFailed {
-				payload.Set("executionStatus", "FAILED")
-			} else {
-				payload.Set("executionStatus", "DONE")
-			}
-			payload.Set("uniqId", c.UniqID)
-			payload.Set("branch", c.Branch)
-			payload.Set("spec", spec)
-			if err != nil {
-				payload.Set("executionErrorOutput", err.Error())
-			} else {
-				payload.Set("executionErrorOutput", "")
-			}
-			if encoded {
-				payload.Set("encoded", "true")
-			}
+	status := StatusDone
+	if executionFailed {
+		status = StatusFailed
+		if c.cancelled.Load() {
+			status = StatusCancelled
+		}
+	}
+	c.report(err, spec, specReport{status: status, result: result, encoded: encoded})
+}
 
-			_, _, err = httprequests.PerformRequests(headers, "POST", fmt.Sprintf("%s%s", c.ApiURL, apiURI), payload.Encode(), "")
-			if err != nil {
-				log.Error().Err(err).Msg("Fail to report back result")
-				return
-			}
-		} else {
-			specs := strings.Split(c.Specs, ",")
-			for _, spec := range specs {
-				payload := url.Values{}
-				payload.Set("result", result)
-				if executionFailed {
-					payload.Set("executionStatus", "FAILED")
-				} else {
-					payload.Set("executionStatus", "DONE")
-				}
-				payload.Set("uniqId", c.UniqID)
-				payload.Set("branch", c.Branch)
-				payload.Set("spec", spec)
-				if err != nil {
-					payload.Set("executionErrorOutput", err.Error())
-				} else {
-					payload.Set("executionErrorOutput", "")
-				}
-				if encoded {
-					payload.Set("encoded", "true")
-				}
+// report sends the report of the spec to the api or logs it when ReportBack is disabled.
+// When spec is empty, the report is sent for all specs and when the browser
+// of the report is empty, it is sent for all browsers. Nothing is reported on dry runs
+func (c *Cypress) report(err error, spec string, r specReport) {
+	if c.DryRun {
+		return
+	}
+	headers := make(map[string]string)
+	headers["Content-Type"] = "application/x-www-form-urlencoded"
 
-				_, _, err = httprequests.PerformRequests(headers, "POST", fmt.Sprintf("%s%s", c.ApiURL, apiURI), payload.Encode(), "")
-				if err != nil {
-					log.Error().Err(err).Msg("Fail to report back result")
-					return
-				}
-			}
+	specs := []string{spec}
+	if spec == "" {
+		specs = c.specList()
+	}
+	browsers := []string{r.browser}
+	if r.browser == "" {
+		browsers = c.browsers()
+	}
+	var executionErrorOutput string
+	if err != nil {
+		executionErrorOutput = err.Error()
+	}
+
+	units := c.unitsOf(specs, browsers)
+	for _, u := range units {
+		c.recordJUnit(err, u, r)
+		c.recordHTML(err, u, r)
+	}
+	for _, u := range units {
+		payload := url.Values{}
+		payload.Set("result", r.result)
+		payload.Set("executionStatus", r.status)
+		payload.Set("uniqId", c.UniqID)
+		payload.Set("branch", c.Branch)
+		payload.Set("spec", u.spec)
+		payload.Set("browser", u.browser)
+		payload.Set("executionErrorOutput", executionErrorOutput)
+		if c.shard != nil {
+			payload.Set("shard", c.shard.String())
 		}
-	} else {
-		var executionStatus string
-		if executionFailed {
-			executionStatus = "FAILED"
-		} else {
-			executionStatus = "DONE"
+		if r.encoded {
+			payload.Set("encoded", "true")
 		}
-		if spec != "" {
-			if err != nil {
-				log.Info().Msgf("result: %s, executionStatus: %s, uniqId: %s, branch: %s, spec: %s, executionErrorOutput: %s", result, executionStatus, c.UniqID, c.Branch, spec, err.Error())
-			} else {
-				log.Info().Msgf("result: %s, executionStatus: %s, uniqId: %s, branch: %s, spec: %s, executionErrorOutput: %s", result, executionStatus, c.UniqID, c.Branch, spec, "")
-			}
-		} else {
-			specs := strings.Split(c.Specs, ",")
-			for _, spec := range specs {
-				if err != nil {
-					log.Info().Msgf("result: %s, executionStatus: %s, uniqId: %s, branch: %s, spec: %s, executionErrorOutput: %s", result, executionStatus, c.UniqID, c.Branch, spec, err.Error())
-				} else {
-					log.Info().Msgf("result: %s, executionStatus: %s, uniqId: %s, branch: %s, spec: %s, executionErrorOutput: %s", result, executionStatus, c.UniqID, c.Branch, spec, "")
-				}
+		if r.duration > 0 {
+			payload.Set("duration", strconv.FormatInt(r.duration, 10))
+		}
+		if c.depsCache.status != "" {
+			payload.Set("depsCache", c.depsCache.status)
+			payload.Set("depsCacheSaved", strconv.FormatInt(c.depsCache.saved.Milliseconds(), 10))
+		}
+		for key, value := range map[string]string{
+			"packageVersion":  c.versions.Package,
+			"binaryVersion":   c.versions.Binary,
+			"electronVersion": c.versions.Electron,
+			"nodeVersion":     c.versions.Node,
+		} {
+			if value != "" {
+				payload.Set(key, value)
 			}
 		}
+		if len(r.attempts) > 0 {
+			payload.Set("attempts", strconv.Itoa(len(r.attempts)))
+			payload.Set("attemptsResult", r.encodeAttempts())
+		}
+		if r.stats != nil {
+			stats, _ := json.Marshal(r.stats)
+			tests, _ := json.Marshal(r.tests)
+			payload.Set("stats", string(stats))
+			payload.Set("tests", string(tests))
+		}
+		if len(r.artifacts) > 0 {
+			urls, _ := json.Marshal(r.artifacts)
+			payload.Set("artifacts", string(urls))
+		}
+
+		if !c.ReportBack {
+			log.Info().Msgf("result: %s, executionStatus: %s, uniqId: %s, branch: %s, spec: %s, browser: %s, executionErrorOutput: %s, attempts: %d", r.result, r.status, c.UniqID, c.Branch, u.spec, u.browser, executionErrorOutput, len(r.attempts))
+			continue
+		}
+
+		_, _, err = httprequests.PerformRequests(headers, "POST", fmt.Sprintf("%s%s", c.ApiURL, apiURI), payload.Encode(), "")
+		if err != nil {
+			log.Error().Err(err).Msg("Fail to report back result")
+			return
+		}
+	}
+}
+
+// specReport is the outcome of a spec sent back to the api
+type specReport struct {
+	browser   string        // browser in which the spec ran, all browsers when empty
+	status    string        // execution status
+	duration  int64         // wall-clock duration of all attempts in milliseconds, 0 when not run
+	result    string        // result of the last attempt
+	content   []byte        // compacted mochawesome result of the last attempt, nil when not available
+	encoded   bool          // whether result is hex encoded
+	attempts  [][]byte      // compacted mochawesome result of each attempt when retries are enabled
+	artifacts []string      // urls of the uploaded screenshots and videos
+	stats     *runner.Stats // stats of the tests of the last attempt, nil when not available
+	tests     []runner.Test // outcome of each test of the last attempt
+}
+
+// encodeAttempts returns the hex encoded json array of each attempt result
+func (r specReport) encodeAttempts() string {
+	results := make([][]byte, len(r.attempts))
+	for i, result := range r.attempts {
+		results[i] = result
+		if result == nil {
+			results[i] = []byte("{}")
+		}
 	}
+	return hex.EncodeToString([]byte(fmt.Sprintf("[%s]", bytes.Join(results, []byte(",")))))
 }
@@ -3,15 +3,28 @@ package cypress
 
 import (
 	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/Lord-Y/cypress-parallel-cli/executor"
+	"github.com/Lord-Y/cypress-parallel-cli/runner"
+	"github.com/Lord-Y/cypress-parallel-cli/specs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -269,3 +282,1336 @@ func TestReportBackspec_specific(t *testing.T) {
 	c.ApiURL = ts.URL
 	c.reportBack(fmt.Errorf("Execution failed"), "cypress/e2e/2-advanced-examples/connectors.cy.js", true, "{}", false)
 }
+
+func TestMaxParallel(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		maxParallel int
+		specs       int
+		expected    int
+	}{
+		{
+			maxParallel: 4,
+			specs:       60,
+			expected:    4,
+		},
+		{
+			maxParallel: 4,
+			specs:       2,
+			expected:    2,
+		},
+		{
+			maxParallel: 4,
+			specs:       0,
+			expected:    1,
+		},
+	}
+
+	for _, tc := range tests {
+		c := Cypress{MaxParallel: tc.maxParallel}
+		assert.Equal(tc.expected, c.maxParallel(tc.specs))
+	}
+
+	var c Cypress
+	assert.GreaterOrEqual(c.maxParallel(1000), 1)
+	assert.LessOrEqual(c.maxParallel(1000), defaultMaxParallel())
+}
+
+func TestWorkers(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		cpus     int
+		cpu      float64
+		memory   int64
+		expected int
+	}{
+		{
+			cpus:     8,
+			expected: 8,
+		},
+		{
+			cpus:     8,
+			cpu:      2.5,
+			expected: 2,
+		},
+		{
+			cpus:     8,
+			cpu:      0.5,
+			expected: 1,
+		},
+		{
+			cpus:     8,
+			cpu:      4,
+			memory:   2 * memoryPerWorker,
+			expected: 2,
+		},
+		{
+			cpus:     8,
+			memory:   memoryPerWorker / 2,
+			expected: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(tc.expected, workers(tc.cpus, tc.cpu, tc.memory))
+	}
+}
+
+func TestReportFilename(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		spec     string
+		expected string
+	}{
+		{
+			spec:     "cypress/e2e/2-advanced-examples/connectors.cy.js",
+			expected: "cypress_e2e_2-advanced-examples_connectors",
+		},
+		{
+			spec:     "cypress/integration/login.spec.js",
+			expected: "cypress_integration_login",
+		},
+		{
+			spec:     "./cypress/e2e/admin/users.cy.ts",
+			expected: "cypress_e2e_admin_users",
+		},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(tc.expected, reportFilename(tc.spec))
+	}
+}
+
+func TestRun_fail_shard(t *testing.T) {
+	assert := assert.New(t)
+	var c Cypress
+	c.Specs = "cypress/e2e/2-advanced-examples/connectors.cy.js"
+	c.Shard = "3/2"
+
+	err := c.Run()
+	assert.Error(err)
+	assert.Equal(ExitTestFailure, ExitCode(err))
+}
+
+func TestSpecList_shard(t *testing.T) {
+	assert := assert.New(t)
+	var c Cypress
+	c.Specs = "c.cy.js,a.cy.js,b.cy.js"
+	c.shard = &specs.Shard{Index: 2, Total: 2}
+
+	assert.Equal([]string{"c.cy.js,a.cy.js,b.cy.js"}, c.specList())
+
+	c.specs = []string{"a.cy.js"}
+	assert.Equal([]string{"a.cy.js"}, c.specList())
+}
+
+func TestRun_shard_unresolved(t *testing.T) {
+	assert := assert.New(t)
+
+	var payloads []url.Values
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(r.ParseForm())
+		mu.Lock()
+		payloads = append(payloads, r.PostForm)
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	var c Cypress
+	c.Repository = filepath.Join(t.TempDir(), "missing")
+	c.Specs = "cypress/e2e,cypress/component/*.cy.js"
+	c.Shard = "2/3"
+	c.UniqID = "uid"
+	c.ReportBack = true
+	c.ApiURL = ts.URL
+	c.Timeout = timeout
+	c.Executor = fakeCypress()
+
+	assert.Equal(ExitInfrastructure, ExitCode(c.Run()))
+	assert.Len(payloads, 1)
+	if len(payloads) == 1 {
+		assert.Equal("cypress/e2e,cypress/component/*.cy.js", payloads[0].Get("spec"))
+		assert.Equal("2/3", payloads[0].Get("shard"))
+		assert.Equal(StatusFailed, payloads[0].Get("executionStatus"))
+	}
+}
+
+func TestWriteTimings(t *testing.T) {
+	assert := assert.New(t)
+	var c Cypress
+	c.TimingsOutput = filepath.Join(t.TempDir(), "timings.json")
+	assert.NoError(specs.Timings{"a.cy.js": 1000, "b.cy.js": 2000}.Write(c.TimingsOutput))
+
+	c.recordDuration("b.cy.js", 3*time.Second)
+	c.recordDuration("c.cy.js", 1500*time.Millisecond)
+	c.writeTimings()
+
+	z, err := specs.ReadTimings(c.TimingsOutput)
+	assert.NoError(err)
+	assert.Equal(specs.Timings{"a.cy.js": 1000, "b.cy.js": 3000, "c.cy.js": 1500}, z)
+
+	d, ok := c.duration("c.cy.js")
+	assert.True(ok)
+	assert.Equal(int64(1500), d)
+}
+
+func TestRun_timingsOutput(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	repository := newRepository(t, map[string]string{
+		"package.json":            `{}`,
+		"cypress/e2e/login.cy.js": "",
+	})
+	// relative paths are resolved from the directory the cli is started from
+	wd, err := os.Getwd()
+	assert.NoError(err)
+	defer os.Chdir(wd)
+	dir := t.TempDir()
+	assert.NoError(os.Chdir(dir))
+
+	var c Cypress
+	c.Repository = repository
+	c.Specs = "cypress/e2e"
+	c.UniqID = "uid"
+	c.Browser = "chrome"
+	c.Timeout = timeout
+	c.Executor = fakeCypress()
+	c.TimingsOutput = "timings.json"
+
+	assert.NoError(c.Run())
+	z, err := specs.ReadTimings(filepath.Join(dir, "timings.json"))
+	assert.NoError(err)
+	assert.Contains(z, "cypress/e2e/login.cy.js")
+}
+
+func TestReport_flaky(t *testing.T) {
+	assert := assert.New(t)
+	var payload url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		payload = r.PostForm
+		fmt.Fprintln(w, "hello")
+	}))
+	defer ts.Close()
+
+	var c Cypress
+	c.Specs = "cypress/e2e/2-advanced-examples/connectors.cy.js"
+	c.UniqID = "uid"
+	c.ReportBack = true
+	c.ApiURL = ts.URL
+
+	r := specReport{
+		status:   StatusFlaky,
+		result:   hex.EncodeToString([]byte(`{"stats":{"failures":0}}`)),
+		encoded:  true,
+		attempts: [][]byte{nil, []byte(`{"stats":{"failures":0}}`)},
+	}
+	c.report(nil, "cypress/e2e/2-advanced-examples/connectors.cy.js", r)
+
+	assert.Equal("FLAKY", payload.Get("executionStatus"))
+	assert.Equal("2", payload.Get("attempts"))
+	assert.Equal("true", payload.Get("encoded"))
+	attempts, err := hex.DecodeString(payload.Get("attemptsResult"))
+	assert.NoError(err)
+	assert.Equal(`[{},{"stats":{"failures":0}}]`, string(attempts))
+}
+
+func TestReport_versions(t *testing.T) {
+	assert := assert.New(t)
+	var payload url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		payload = r.PostForm
+		fmt.Fprintln(w, "hello")
+	}))
+	defer ts.Close()
+
+	var c Cypress
+	c.UniqID = "uid"
+	c.ReportBack = true
+	c.ApiURL = ts.URL
+	c.versions = runner.Version{Package: "10.10.0", Binary: "10.10.0", Electron: "19.0.8", Node: "16.14.2"}
+
+	c.reportBack(nil, "cypress/e2e/login.cy.js", false, "{}", false)
+	assert.Equal("10.10.0", payload.Get("packageVersion"))
+	assert.Equal("10.10.0", payload.Get("binaryVersion"))
+	assert.Equal("19.0.8", payload.Get("electronVersion"))
+	assert.Equal("16.14.2", payload.Get("nodeVersion"))
+}
+
+// newRepository creates a local git repository with the given files and returns its path
+func newRepository(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = w.Commit("init", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// withoutCypress hides cypress installed on the host so versions are parsed from the fake cypress --version.
+// Only git, required to clone local repositories, is kept in PATH
+func withoutCypress(t *testing.T) {
+	bin := t.TempDir()
+	if program, err := exec.LookPath("git"); err == nil {
+		if err := os.Symlink(program, filepath.Join(bin, "git")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin)
+	t.Setenv("CYPRESS_CACHE_FOLDER", t.TempDir())
+}
+
+// fakeCypress returns an executor simulating cypress and npm.
+// Specs listed in failures fail, the others pass. Failures formatted like spec@browser only fail in the browser
+func fakeCypress(failures ...string) *executor.Recorder {
+	return &executor.Recorder{
+		Handler: func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+			if cmd.Name != "cypress" {
+				return nil, nil
+			}
+			if cmd.Args[0] == "--version" {
+				return []byte("Cypress package version: 10.10.0\nCypress binary version: 10.10.0\n"), nil
+			}
+			if cmd.Args[0] == "info" {
+				return []byte("1. Chrome\n  - Name: chrome\n  - Channel: stable\n\n2. Firefox\n  - Name: firefox\n  - Channel: stable\n"), nil
+			}
+
+			var spec, browser, reportFilename string
+			dir := filepath.Join(cmd.Dir, "mochawesome-report")
+			for i, arg := range cmd.Args {
+				switch arg {
+				case "--browser":
+					browser = cmd.Args[i+1]
+				case "--spec":
+					spec = cmd.Args[i+1]
+				case "--reporter-options":
+					for _, option := range strings.Split(cmd.Args[i+1], ",") {
+						key, value, _ := strings.Cut(option, "=")
+						switch key {
+						case "reportFilename":
+							reportFilename = value
+						case "reportDir":
+							dir = value
+						}
+					}
+				}
+			}
+			failed := 0
+			for _, failure := range failures {
+				if failure == spec || failure == spec+"@"+browser {
+					failed = 1
+				}
+			}
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return nil, err
+			}
+			result := fmt.Sprintf(`{"stats": {"tests": 1, "passes": %d, "failures": %d}}`, 1-failed, failed)
+			if err := os.WriteFile(filepath.Join(dir, reportFilename+".json"), []byte(result), 0644); err != nil {
+				return nil, err
+			}
+			if failed > 0 {
+				return nil, fmt.Errorf("exit status 1")
+			}
+			return nil, nil
+		},
+	}
+}
+
+// commandLines returns the command line of each command
+func commandLines(commands []executor.Command) (z []string) {
+	for _, cmd := range commands {
+		z = append(z, cmd.String())
+	}
+	return
+}
+
+func TestRun_executor(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	repository := newRepository(t, map[string]string{
+		"package.json":                  `{"devDependencies": {"cypress": "10.10.0", "cypress-real-events": "1.7.0"}}`,
+		"cypress.config.js":             "",
+		"cypress/e2e/login.cy.js":       "",
+		"cypress/e2e/admin/users.cy.js": "",
+	})
+
+	tests := []struct {
+		failures []string
+		code     int
+	}{
+		{
+			code: ExitSuccess,
+		},
+		{
+			failures: []string{"cypress/e2e/login.cy.js"},
+			code:     ExitTestFailure,
+		},
+	}
+
+	for _, tc := range tests {
+		r := fakeCypress(tc.failures...)
+		var c Cypress
+		c.Repository = repository
+		c.Specs = "cypress/e2e/**/*.cy.js"
+		c.UniqID = "uid"
+		c.Browser = "chrome"
+		c.ConfigFile = "cypress.config.js"
+		c.Timeout = timeout
+		c.MaxParallel = 1
+		c.Executor = r
+
+		err := c.Run()
+		assert.Equal(tc.code, ExitCode(err))
+		assert.Equal(
+			[]string{
+				"npm install",
+				"npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome",
+				"cypress --version",
+				"cypress info",
+				"cypress run --browser chrome --config-file cypress.config.js --spec cypress/e2e/admin/users.cy.js --reporter ./.cypress-parallel-cli/node_modules/mochawesome --reporter-options reportFilename=cypress_e2e_admin_users",
+				"cypress run --browser chrome --config-file cypress.config.js --spec cypress/e2e/login.cy.js --reporter ./.cypress-parallel-cli/node_modules/mochawesome --reporter-options reportFilename=cypress_e2e_login",
+			},
+			commandLines(r.Commands()),
+		)
+	}
+}
+
+func TestRun_specs_braces(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	repository := newRepository(t, map[string]string{
+		"package.json":                  `{}`,
+		"cypress/e2e/login.cy.js":       "",
+		"cypress/e2e/admin/users.cy.ts": "",
+		"cypress/support/e2e.js":        "",
+	})
+
+	r := fakeCypress()
+	var c Cypress
+	c.Repository = repository
+	c.Specs = "cypress/e2e/**/*.cy.{js,ts},cypress/e2e/login.cy.js"
+	c.UniqID = "uid"
+	c.Browser = "chrome"
+	c.Timeout = timeout
+	c.Executor = r
+
+	assert.NoError(c.Run())
+	var resolved []string
+	for _, cmd := range r.Commands() {
+		for i, arg := range cmd.Args {
+			if arg == "--spec" {
+				resolved = append(resolved, cmd.Args[i+1])
+			}
+		}
+	}
+	assert.ElementsMatch([]string{"cypress/e2e/admin/users.cy.ts", "cypress/e2e/login.cy.js"}, resolved)
+}
+
+func TestRun_timeout_queued(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	statuses := make(map[string]string)
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(r.ParseForm())
+		mu.Lock()
+		statuses[r.PostForm.Get("spec")] = r.PostForm.Get("executionStatus")
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	r := fakeCypress()
+	handler := r.Handler
+	r.Handler = func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+		if cmd.Name == "cypress" && cmd.Args[0] == "run" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return handler(ctx, cmd)
+	}
+
+	var c Cypress
+	c.Repository = newRepository(t, map[string]string{
+		"package.json":                  `{}`,
+		"cypress/e2e/login.cy.js":       "",
+		"cypress/e2e/admin/users.cy.js": "",
+	})
+	c.Specs = "cypress/e2e"
+	c.UniqID = "uid"
+	c.Browser = "chrome"
+	c.MaxParallel = 1
+	c.ReportBack = true
+	c.ApiURL = ts.URL
+	c.Executor = r
+	c.timeout = time.Second
+
+	assert.Equal(ExitTimeout, ExitCode(c.Run()))
+	var runs int
+	for _, cmd := range r.Commands() {
+		if cmd.Name == "cypress" && cmd.Args[0] == "run" {
+			runs++
+		}
+	}
+	// the queued spec is not started once the timeout is reached
+	assert.Equal(1, runs)
+	assert.Equal(map[string]string{
+		"cypress/e2e/admin/users.cy.js": StatusTimeout,
+		"cypress/e2e/login.cy.js":       StatusTimeout,
+	}, statuses)
+}
+
+func TestRun_parallel(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	wd, err := os.Getwd()
+	assert.NoError(err)
+
+	paths := []string{"cypress/e2e/login.cy.js", "cypress/e2e/admin/users.cy.js"}
+	recorders := make([]*executor.Recorder, len(paths))
+	errs := make([]error, len(paths))
+	var wg sync.WaitGroup
+	for i, spec := range paths {
+		repository := newRepository(t, map[string]string{
+			"package.json": `{}`,
+			spec:           "",
+		})
+		recorders[i] = fakeCypress()
+
+		var c Cypress
+		c.Repository = repository
+		c.Specs = "cypress/e2e/**/*.cy.js"
+		c.UniqID = "uid"
+		c.Browser = "chrome"
+		c.Timeout = timeout
+		c.Executor = recorders[i]
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = c.Run()
+		}(i)
+	}
+	wg.Wait()
+
+	for i, spec := range paths {
+		assert.NoError(errs[i])
+		var dirs []string
+		for _, cmd := range recorders[i].Commands() {
+			if cmd.Name == "cypress" && cmd.Args[0] == "run" {
+				assert.Contains(cmd.Args, spec)
+				dirs = append(dirs, cmd.Dir)
+			}
+		}
+		assert.Len(dirs, 1)
+		// every command of the run is started in its own checkout
+		for _, cmd := range recorders[i].Commands() {
+			if len(dirs) > 0 {
+				assert.Equal(dirs[0], cmd.Dir)
+			}
+		}
+	}
+
+	current, err := os.Getwd()
+	assert.NoError(err)
+	assert.Equal(wd, current)
+}
+
+func TestRun_executor_fail_install(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+
+	r := fakeCypress()
+	handler := r.Handler
+	r.Handler = func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+		if cmd.String() == "npm install" {
+			return nil, fmt.Errorf("exit status 1")
+		}
+		return handler(ctx, cmd)
+	}
+
+	var c Cypress
+	c.Repository = newRepository(t, map[string]string{
+		"package.json":            `{}`,
+		"cypress/e2e/login.cy.js": "",
+	})
+	c.Specs = "cypress/e2e/login.cy.js"
+	c.UniqID = "uid"
+	c.Timeout = timeout
+	c.Executor = r
+
+	err := c.Run()
+	assert.Equal(ExitInfrastructure, ExitCode(err))
+	assert.Equal([]string{"npm install"}, commandLines(r.Commands()))
+}
+
+func TestRun_executor_timeout(t *testing.T) {
+	assert := assert.New(t)
+
+	r := fakeCypress()
+	var c Cypress
+	c.Repository = newRepository(t, map[string]string{
+		"package.json":            `{}`,
+		"cypress/e2e/login.cy.js": "",
+	})
+	c.Specs = "cypress/e2e/login.cy.js"
+	c.UniqID = "uid"
+	c.Timeout = 0
+	c.Executor = r
+
+	err := c.Run()
+	assert.Equal(ExitTimeout, ExitCode(err))
+	assert.Empty(r.Commands())
+}
+
+func TestRun_playwright(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("DISPLAY", "")
+
+	r := &executor.Recorder{
+		Handler: func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+			if cmd.String() == "npx --no-install playwright --version" {
+				return []byte("Version 1.40.1\n"), nil
+			}
+			for _, env := range cmd.Env {
+				if result := strings.TrimPrefix(env, "PLAYWRIGHT_JSON_OUTPUT_NAME="); result != env {
+					if err := os.MkdirAll(filepath.Dir(result), 0755); err != nil {
+						return nil, err
+					}
+					return nil, os.WriteFile(result, []byte(`{"stats": {"expected": 1, "unexpected": 0}}`), 0644)
+				}
+			}
+			return nil, nil
+		},
+	}
+	var c Cypress
+	c.Repository = newRepository(t, map[string]string{
+		"package.json":        `{"devDependencies": {"@playwright/test": "1.40.1"}}`,
+		"tests/login.spec.ts": "",
+	})
+	c.Specs = "tests/**/*.spec.ts"
+	c.UniqID = "uid"
+	c.Timeout = timeout
+	c.Executor = r
+	c.Runner = runner.Playwright{}
+
+	assert.NoError(c.Run())
+	assert.Equal(
+		[]string{
+			"npm install",
+			"npx --no-install playwright --version",
+			"npx playwright test tests/login.spec.ts --reporter json --workers 1",
+		},
+		commandLines(r.Commands()),
+	)
+}
+
+func TestRun_specTimeout(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	var (
+		mu       sync.Mutex
+		statuses = make(map[string]string)
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		mu.Lock()
+		statuses[r.PostForm.Get("spec")] = r.PostForm.Get("executionStatus")
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	r := fakeCypress()
+	handler := r.Handler
+	r.Handler = func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+		for _, arg := range cmd.Args {
+			if arg == "cypress/e2e/slow/upload.cy.js" {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+		}
+		return handler(ctx, cmd)
+	}
+
+	var c Cypress
+	c.Repository = newRepository(t, map[string]string{
+		"package.json":                  `{}`,
+		"manifest.json":                 `{"specs": [{"pattern": "cypress/e2e/slow/**", "timeout": "100ms"}]}`,
+		"cypress/e2e/login.cy.js":       "",
+		"cypress/e2e/slow/upload.cy.js": "",
+	})
+	c.Specs = "cypress/e2e"
+	c.UniqID = "uid"
+	c.Browser = "chrome"
+	c.Timeout = timeout
+	c.SpecTimeout = time.Minute
+	c.Manifest = "manifest.json"
+	c.MaxParallel = 2
+	c.ReportBack = true
+	c.ApiURL = ts.URL
+	c.Executor = r
+
+	start := time.Now()
+	err := c.Run()
+	assert.Less(time.Since(start), time.Minute)
+	assert.Equal(ExitTimeout, ExitCode(err))
+	assert.Equal(map[string]string{
+		"cypress/e2e/login.cy.js":       StatusDone,
+		"cypress/e2e/slow/upload.cy.js": StatusTimeout,
+	}, statuses)
+}
+
+func TestRun_cancelled(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	var (
+		mu       sync.Mutex
+		payloads = make(map[string]url.Values)
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		mu.Lock()
+		payloads[r.PostForm.Get("spec")] = r.PostForm
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	r := fakeCypress()
+	handler := r.Handler
+	r.Handler = func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+		if cmd.Name != "cypress" || cmd.Args[0] != "run" {
+			return handler(ctx, cmd)
+		}
+		dir := filepath.Join(cmd.Dir, "mochawesome-report")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cypress_e2e_admin_users.json"), []byte(`{"stats": {"tests": 1}}`), 0644); err != nil {
+			return nil, err
+		}
+		for i, arg := range cmd.Args {
+			if arg != "--config" {
+				continue
+			}
+			for _, value := range strings.Split(cmd.Args[i+1], ",") {
+				if key, folder, _ := strings.Cut(value, "="); key == "screenshotsFolder" {
+					if err := os.MkdirAll(folder, 0755); err != nil {
+						return nil, err
+					}
+					if err := os.WriteFile(filepath.Join(folder, "failed.png"), []byte("png"), 0644); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		// the pod is evicted while the spec is running
+		if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+			return nil, err
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	var c Cypress
+	c.Repository = newRepository(t, map[string]string{
+		"package.json":                  `{}`,
+		"cypress/e2e/admin/users.cy.js": "",
+		"cypress/e2e/login.cy.js":       "",
+	})
+	c.Specs = "cypress/e2e"
+	c.UniqID = "uid"
+	c.Browser = "chrome"
+	c.Timeout = timeout
+	c.MaxParallel = 1
+	c.ReportBack = true
+	c.ApiURL = ts.URL
+	c.Executor = r
+	uploader := &fakeUploader{}
+	c.uploader = uploader
+
+	err := c.Run()
+	assert.Equal(ExitCancelled, ExitCode(err))
+	// artifacts are not uploaded within the grace period
+	assert.Empty(uploader.keys)
+	assert.Len(payloads, 2)
+	running := payloads["cypress/e2e/admin/users.cy.js"]
+	assert.Equal(StatusCancelled, running.Get("executionStatus"))
+	assert.Equal("true", running.Get("encoded"))
+	result, err := hex.DecodeString(running.Get("result"))
+	assert.NoError(err)
+	assert.Equal(`{"stats":{"tests":1}}`, string(result))
+	queued := payloads["cypress/e2e/login.cy.js"]
+	assert.Equal(StatusCancelled, queued.Get("executionStatus"))
+	assert.Equal("{}", queued.Get("result"))
+}
+
+func TestRun_cancelled_twice(t *testing.T) {
+	assert := assert.New(t)
+	if os.Getenv("SIGNAL_TWICE") == "1" {
+		withoutCypress(t)
+		t.Setenv("DISPLAY", ":0")
+		r := fakeCypress()
+		handler := r.Handler
+		r.Handler = func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+			if cmd.Name != "cypress" || cmd.Args[0] != "run" {
+				return handler(ctx, cmd)
+			}
+			_ = syscall.Kill(os.Getpid(), syscall.SIGTERM)
+			<-ctx.Done()
+			// the spec ignores the first signal and hangs
+			_ = syscall.Kill(os.Getpid(), syscall.SIGTERM)
+			select {}
+		}
+
+		var c Cypress
+		c.Repository = newRepository(t, map[string]string{
+			"package.json":            `{}`,
+			"cypress/e2e/login.cy.js": "",
+		})
+		c.Specs = "cypress/e2e"
+		c.UniqID = "uid"
+		c.Browser = "chrome"
+		c.Timeout = timeout
+		c.Executor = r
+		c.Run()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=TestRun_cancelled_twice")
+	cmd.Env = append(os.Environ(), "SIGNAL_TWICE=1")
+	err := cmd.Run()
+	assert.NoError(ctx.Err())
+	e, ok := err.(*exec.ExitError)
+	if assert.True(ok) {
+		status := e.Sys().(syscall.WaitStatus)
+		assert.True(status.Signaled())
+		assert.Equal(syscall.SIGTERM, status.Signal())
+	}
+}
+
+func TestMaxFailures(t *testing.T) {
+	tests := []struct {
+		failFast    bool
+		maxFailures int
+		expected    int
+	}{
+		{
+			expected: 0,
+		},
+		{
+			failFast: true,
+			expected: 1,
+		},
+		{
+			maxFailures: 3,
+			expected:    3,
+		},
+		{
+			failFast:    true,
+			maxFailures: 3,
+			expected:    3,
+		},
+	}
+
+	assert := assert.New(t)
+	for _, tc := range tests {
+		c := Cypress{FailFast: tc.failFast, MaxFailures: tc.maxFailures}
+		assert.Equal(tc.expected, c.maxFailures())
+	}
+}
+
+func TestRun_failFast(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	var (
+		mu       sync.Mutex
+		statuses = make(map[string]string)
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		mu.Lock()
+		statuses[r.PostForm.Get("spec")] = r.PostForm.Get("executionStatus")
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	started := make(chan struct{})
+	r := fakeCypress("cypress/e2e/a.cy.js")
+	handler := r.Handler
+	r.Handler = func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+		for _, arg := range cmd.Args {
+			switch arg {
+			case "cypress/e2e/a.cy.js":
+				// fail once b is running
+				<-started
+			case "cypress/e2e/b.cy.js":
+				close(started)
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+		}
+		return handler(ctx, cmd)
+	}
+
+	var c Cypress
+	c.Repository = newRepository(t, map[string]string{
+		"package.json":        `{}`,
+		"cypress/e2e/a.cy.js": "",
+		"cypress/e2e/b.cy.js": "",
+		"cypress/e2e/c.cy.js": "",
+	})
+	c.Specs = "cypress/e2e"
+	c.UniqID = "uid"
+	c.Browser = "chrome"
+	c.Timeout = timeout
+	c.MaxParallel = 2
+	c.FailFast = true
+	c.ReportBack = true
+	c.ApiURL = ts.URL
+	c.Executor = r
+
+	err := c.Run()
+	assert.Equal(ExitTestFailure, ExitCode(err))
+	assert.Equal(map[string]string{
+		"cypress/e2e/a.cy.js": StatusFailed,
+		"cypress/e2e/b.cy.js": StatusCancelled,
+		"cypress/e2e/c.cy.js": StatusSkipped,
+	}, statuses)
+	for _, line := range commandLines(r.Commands()) {
+		assert.NotContains(line, "cypress/e2e/c.cy.js")
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected map[string]string
+		fail     bool
+	}{
+		{
+			s: "",
+		},
+		{
+			s:        "user=admin,url=http://localhost:8080/?a=b",
+			expected: map[string]string{"user": "admin", "url": "http://localhost:8080/?a=b"},
+		},
+		{
+			s:        "empty=",
+			expected: map[string]string{"empty": ""},
+		},
+		{
+			s:    "user",
+			fail: true,
+		},
+		{
+			s:    "=admin",
+			fail: true,
+		},
+	}
+
+	assert := assert.New(t)
+	for _, tc := range tests {
+		z, err := parseValues(tc.s)
+		if tc.fail {
+			assert.Error(err)
+			continue
+		}
+		assert.NoError(err)
+		assert.Equal(tc.expected, z)
+	}
+}
+
+func TestExecution(t *testing.T) {
+	assert := assert.New(t)
+	headless := false
+	var c Cypress
+	c.Browser = "chrome"
+	c.ConfigFile = "cypress.config.ts"
+	c.Headed = true
+	c.env = map[string]string{"user": "admin", "lang": "en"}
+	c.manifest = specs.Manifest{
+		Specs: []specs.Settings{
+			{Pattern: "cypress/e2e/fr/**", Env: specs.Values{"lang": "fr"}, Config: specs.Values{"baseUrl": "https://fr.example.com"}, Headed: &headless},
+		},
+	}
+
+	x := c.execution("/tmp/project", "10.10.0", unit{spec: "cypress/e2e/fr/login.cy.js", browser: "chrome"}, "cypress_e2e_fr_login")
+	assert.Equal(runner.Execution{
+		Dir:       "/tmp/project",
+		Spec:      "cypress/e2e/fr/login.cy.js",
+		Browser:   "chrome",
+		Config:    "cypress.config.ts",
+		Version:   "10.10.0",
+		Report:    "cypress_e2e_fr_login",
+		Env:       map[string]string{"user": "admin", "lang": "fr"},
+		Overrides: map[string]string{"baseUrl": "https://fr.example.com"},
+	}, x)
+
+	x = c.execution("/tmp/project", "10.10.0", unit{spec: "cypress/e2e/login.cy.js", browser: "chrome"}, "cypress_e2e_login")
+	assert.True(x.Headed)
+	assert.Equal(map[string]string{"user": "admin", "lang": "en"}, x.Env)
+	assert.Nil(x.Overrides)
+}
+
+func TestRun_browsers(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	var (
+		mu       sync.Mutex
+		statuses = make(map[string]string)
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		mu.Lock()
+		statuses[r.PostForm.Get("spec")+" "+r.PostForm.Get("browser")] = r.PostForm.Get("executionStatus")
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	r := fakeCypress("cypress/e2e/a.cy.js@firefox")
+
+	var c Cypress
+	c.Repository = newRepository(t, map[string]string{
+		"package.json":        `{}`,
+		"cypress/e2e/a.cy.js": "",
+		"cypress/e2e/b.cy.js": "",
+	})
+	c.Specs = "cypress/e2e"
+	c.UniqID = "uid"
+	c.Browser = "chrome, firefox,chrome"
+	c.Timeout = timeout
+	c.MaxParallel = 1
+	c.ReportBack = true
+	c.ApiURL = ts.URL
+	c.Executor = r
+
+	err := c.Run()
+	assert.Equal(ExitTestFailure, ExitCode(err))
+	assert.Equal(
+		[]string{
+			"cypress --version",
+			"cypress info",
+			"cypress run --browser chrome --spec cypress/e2e/a.cy.js --reporter ./.cypress-parallel-cli/node_modules/mochawesome --reporter-options reportFilename=cypress_e2e_a_chrome",
+			"cypress run --browser firefox --spec cypress/e2e/a.cy.js --reporter ./.cypress-parallel-cli/node_modules/mochawesome --reporter-options reportFilename=cypress_e2e_a_firefox",
+			"cypress run --browser chrome --spec cypress/e2e/b.cy.js --reporter ./.cypress-parallel-cli/node_modules/mochawesome --reporter-options reportFilename=cypress_e2e_b_chrome",
+			"cypress run --browser firefox --spec cypress/e2e/b.cy.js --reporter ./.cypress-parallel-cli/node_modules/mochawesome --reporter-options reportFilename=cypress_e2e_b_firefox",
+		},
+		commandLines(r.Commands())[2:],
+	)
+	assert.Equal(map[string]string{
+		"cypress/e2e/a.cy.js chrome":  StatusDone,
+		"cypress/e2e/a.cy.js firefox": StatusFailed,
+		"cypress/e2e/b.cy.js chrome":  StatusDone,
+		"cypress/e2e/b.cy.js firefox": StatusDone,
+	}, statuses)
+}
+
+func TestRun_browsers_missing(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	var (
+		mu       sync.Mutex
+		payloads []url.Values
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		mu.Lock()
+		payloads = append(payloads, r.PostForm)
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	r := fakeCypress()
+	var c Cypress
+	c.Repository = newRepository(t, map[string]string{
+		"package.json":        `{}`,
+		"cypress/e2e/a.cy.js": "",
+	})
+	c.Specs = "cypress/e2e"
+	c.UniqID = "uid"
+	c.Browser = "chrome,electron,webkit,edge:beta"
+	c.Timeout = timeout
+	c.ReportBack = true
+	c.ApiURL = ts.URL
+	c.Executor = r
+
+	err := c.Run()
+	assert.Equal(ExitInfrastructure, ExitCode(err))
+	assert.Contains(err.Error(), "browser(s) webkit,edge:beta not installed")
+	for _, cmd := range commandLines(r.Commands()) {
+		assert.NotContains(cmd, "cypress run")
+	}
+	assert.Len(payloads, 4)
+	for _, payload := range payloads {
+		assert.Equal(StatusFailed, payload.Get("executionStatus"))
+		assert.NotEmpty(payload.Get("browser"))
+	}
+}
+
+func TestReportFilename_browsers(t *testing.T) {
+	tests := []struct {
+		browser  string
+		u        unit
+		expected string
+	}{
+		{
+			browser:  "chrome",
+			u:        unit{spec: "cypress/e2e/login.cy.js", browser: "chrome"},
+			expected: "cypress_e2e_login",
+		},
+		{
+			browser:  "chrome,firefox",
+			u:        unit{spec: "cypress/e2e/login.cy.js", browser: "firefox"},
+			expected: "cypress_e2e_login_firefox",
+		},
+		{
+			browser:  "chrome:beta,/usr/bin/chromium",
+			u:        unit{spec: "cypress/e2e/login.cy.js", browser: "/usr/bin/chromium"},
+			expected: "cypress_e2e_login_usr_bin_chromium",
+		},
+	}
+
+	assert := assert.New(t)
+	for _, tc := range tests {
+		c := Cypress{Browser: tc.browser}
+		assert.Equal(tc.expected, c.reportFilename(tc.u))
+	}
+	assert.Equal([]string{""}, (&Cypress{}).browsers())
+}
+
+// fakeUploader records the keys of uploaded artifacts
+type fakeUploader struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (f *fakeUploader) Upload(ctx context.Context, key string, file string, metadata map[string]string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys = append(f.keys, key)
+	return fmt.Sprintf("https://artifacts.example.com/%s", key), nil
+}
+
+func TestRun_artifacts(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	var (
+		mu        sync.Mutex
+		artifacts = make(map[string]string)
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		mu.Lock()
+		artifacts[r.PostForm.Get("spec")] = r.PostForm.Get("artifacts")
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	// specs write a screenshot and a video in the folders given with --config
+	r := fakeCypress("cypress/e2e/a.cy.js")
+	handler := r.Handler
+	r.Handler = func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+		for i, arg := range cmd.Args {
+			if arg != "--config" {
+				continue
+			}
+			for _, value := range strings.Split(cmd.Args[i+1], ",") {
+				key, folder, _ := strings.Cut(value, "=")
+				file := map[string]string{"screenshotsFolder": "a.cy.js/failed.png", "videosFolder": "a.cy.js.mp4"}[key]
+				if err := os.MkdirAll(filepath.Dir(filepath.Join(folder, file)), 0755); err != nil {
+					return nil, err
+				}
+				if err := os.WriteFile(filepath.Join(folder, file), []byte("artifact"), 0644); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return handler(ctx, cmd)
+	}
+
+	uploader := &fakeUploader{}
+	var c Cypress
+	c.Repository = newRepository(t, map[string]string{
+		"package.json":        `{}`,
+		"cypress/e2e/a.cy.js": "",
+		"cypress/e2e/b.cy.js": "",
+	})
+	c.Specs = "cypress/e2e"
+	c.UniqID = "uid"
+	c.Browser = "chrome"
+	c.Timeout = timeout
+	c.Retries = 1
+	c.ReportBack = true
+	c.ApiURL = ts.URL
+	c.Executor = r
+	c.Artifacts = "api"
+	c.ArtifactsOnFailure = true
+	c.uploader = uploader
+
+	err := c.Run()
+	assert.Equal(ExitTestFailure, ExitCode(err))
+	assert.Equal([]string{
+		"uid/cypress_e2e_a/screenshots/a.cy.js/failed.png",
+		"uid/cypress_e2e_a/videos/a.cy.js.mp4",
+		"uid/cypress_e2e_a_attempt2/screenshots/a.cy.js/failed.png",
+		"uid/cypress_e2e_a_attempt2/videos/a.cy.js.mp4",
+	}, uploader.keys)
+	var urls []string
+	assert.NoError(json.Unmarshal([]byte(artifacts["cypress/e2e/a.cy.js"]), &urls))
+	assert.Len(urls, 4)
+	assert.Equal("https://artifacts.example.com/uid/cypress_e2e_a/screenshots/a.cy.js/failed.png", urls[0])
+	assert.Empty(artifacts["cypress/e2e/b.cy.js"])
+}
+
+func TestRun_artifacts_destination(t *testing.T) {
+	assert := assert.New(t)
+	var c Cypress
+	c.Specs = "cypress/e2e/login.cy.js"
+	c.Artifacts = "gs://artifacts"
+
+	err := c.Run()
+	assert.Error(err)
+	assert.Equal(ExitTestFailure, ExitCode(err))
+}
+
+func TestRun_testResults(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	var (
+		mu       sync.Mutex
+		payloads = make(map[string]url.Values)
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		mu.Lock()
+		payloads[r.PostForm.Get("spec")] = r.PostForm
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	passed := `{"title": "passes", "fullTitle": "suite passes", "duration": 10, "state": "passed", "pass": true, "err": {}}`
+	failed := `{"title": "fails", "fullTitle": "suite fails", "duration": 20, "state": "failed", "fail": true, "code": "expect(1).to.equal(2)", "err": {"message": "AssertionError: expected 1 to equal 2"}}`
+	results := map[string]struct {
+		content string
+		err     error
+	}{
+		// the process failed after all tests passed
+		"cypress/e2e/a.cy.js": {
+			content: fmt.Sprintf(`{"stats": {"tests": 1, "passes": 1}, "results": [{"suites": [{"title": "suite", "tests": [%s]}]}]}`, passed),
+			err:     fmt.Errorf("exit status 1"),
+		},
+		// the process succeeded with a failed test
+		"cypress/e2e/b.cy.js": {
+			content: fmt.Sprintf(`{"stats": {"tests": 2, "passes": 1, "failures": 1}, "results": [{"suites": [{"title": "suite", "tests": [%s, %s]}]}]}`, passed, failed),
+		},
+		// the process failed before running any test
+		"cypress/e2e/c.cy.js": {
+			content: `{"stats": {"tests": 0}, "results": []}`,
+			err:     fmt.Errorf("exit status 1"),
+		},
+	}
+	r := fakeCypress()
+	handler := r.Handler
+	r.Handler = func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+		if cmd.Name != "cypress" || cmd.Args[0] != "run" {
+			return handler(ctx, cmd)
+		}
+		var spec, reportFilename string
+		for i, arg := range cmd.Args {
+			switch arg {
+			case "--spec":
+				spec = cmd.Args[i+1]
+			case "--reporter-options":
+				reportFilename = strings.TrimPrefix(cmd.Args[i+1], "reportFilename=")
+			}
+		}
+		dir := filepath.Join(cmd.Dir, "mochawesome-report")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, reportFilename+".json"), []byte(results[spec].content), 0644); err != nil {
+			return nil, err
+		}
+		return nil, results[spec].err
+	}
+
+	var c Cypress
+	c.Repository = newRepository(t, map[string]string{
+		"package.json":        `{}`,
+		"cypress/e2e/a.cy.js": "",
+		"cypress/e2e/b.cy.js": "",
+		"cypress/e2e/c.cy.js": "",
+	})
+	c.Specs = "cypress/e2e"
+	c.UniqID = "uid"
+	c.Browser = "chrome"
+	c.Timeout = timeout
+	c.ReportBack = true
+	c.ApiURL = ts.URL
+	c.Executor = r
+
+	err := c.Run()
+	assert.Equal(ExitTestFailure, ExitCode(err))
+	assert.Equal(StatusDone, payloads["cypress/e2e/a.cy.js"].Get("executionStatus"))
+	assert.Equal(StatusFailed, payloads["cypress/e2e/b.cy.js"].Get("executionStatus"))
+	assert.Equal(StatusFailed, payloads["cypress/e2e/c.cy.js"].Get("executionStatus"))
+
+	assert.JSONEq(`{"tests": 2, "passes": 1, "failures": 1, "pending": 0, "skipped": 0}`, payloads["cypress/e2e/b.cy.js"].Get("stats"))
+	assert.JSONEq(`[
+		{"title": "suite passes", "state": "passed", "duration": 10},
+		{"title": "suite fails", "state": "failed", "duration": 20, "error": "AssertionError: expected 1 to equal 2", "code": "expect(1).to.equal(2)"}
+	]`, payloads["cypress/e2e/b.cy.js"].Get("tests"))
+	assert.Equal(runner.Stats{Tests: 3, Passes: 2, Failures: 1}, c.stats)
+}
@@ -0,0 +1,97 @@
+// Package cypress assemble all commands required to run cypress unit testing
+package cypress
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Lord-Y/cypress-parallel-cli/depscache"
+	"github.com/Lord-Y/cypress-parallel-cli/executor"
+	"github.com/Lord-Y/cypress-parallel-cli/runner"
+	"github.com/rs/zerolog/log"
+)
+
+// Dependencies cache statuses
+const (
+	DepsCacheHit  = "hit"
+	DepsCacheMiss = "miss"
+)
+
+// depsCacheResult is the outcome of the dependencies cache lookup sent to the api
+type depsCacheResult struct {
+	status string        // hit or miss, empty when the cache is disabled
+	saved  time.Duration // install time saved by restoring dependencies from the cache
+}
+
+// install installs the dependencies of the project cloned in gitdir. When DepsCacheDir is set,
+// they are restored from the cache if they were installed from the same lockfile with the same
+// node version, otherwise they are installed and saved in the cache
+func (c *Cypress) install(ctx context.Context, framework runner.Runner, gitdir string) error {
+	if c.DepsCacheDir == "" {
+		return framework.Install(ctx, c.executor(), gitdir)
+	}
+	key, err := c.depsCacheKey(ctx, framework, gitdir)
+	if err != nil {
+		log.Warn().Err(err).Msg("Error occured while computing dependencies cache key, dependencies are not cached")
+		return framework.Install(ctx, c.executor(), gitdir)
+	}
+
+	cache := depscache.Cache{Dir: c.DepsCacheDir}
+	start := time.Now()
+	entry, ok, err := cache.Restore(key, gitdir)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Error occured while restoring dependencies from cache %s", key)
+	}
+	if ok {
+		restore := time.Since(start)
+		c.depsCache = depsCacheResult{status: DepsCacheHit, saved: entry.Duration - restore}
+		if c.depsCache.saved < 0 {
+			c.depsCache.saved = 0
+		}
+		log.Info().Msgf("Dependencies cache hit %s, restored in %s instead of installed in %s", key, restore.Round(time.Millisecond), entry.Duration.Round(time.Millisecond))
+		return nil
+	}
+	c.depsCache = depsCacheResult{status: DepsCacheMiss}
+	log.Info().Msgf("Dependencies cache miss %s", key)
+
+	start = time.Now()
+	if err = framework.Install(ctx, c.executor(), gitdir); err != nil {
+		return err
+	}
+	entry = depscache.Entry{Dirs: framework.Dependencies(), Duration: time.Since(start)}
+	saved, err := cache.Save(key, gitdir, entry)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Error occured while saving dependencies in cache %s", key)
+		return nil
+	}
+	if saved {
+		log.Debug().Msgf("Dependencies saved in cache %s", key)
+	}
+	return nil
+}
+
+// depsCacheKey returns the cache key of the dependencies of the project cloned in gitdir,
+// hash of its lockfile, of the node version, of the framework, of the package manager and of the cypress version in PATH
+func (c *Cypress) depsCacheKey(ctx context.Context, framework runner.Runner, gitdir string) (string, error) {
+	pm, err := runner.DetectPackageManager(gitdir)
+	if err != nil {
+		return "", err
+	}
+	if pm.Lockfile == "" {
+		return "", fmt.Errorf("no lockfile found")
+	}
+	lockfile, err := os.ReadFile(filepath.Join(gitdir, pm.Lockfile))
+	if err != nil {
+		return "", err
+	}
+	output, err := executor.Output(ctx, c.executor(), executor.Command{Name: "node", Args: []string{"--version"}, Dir: gitdir})
+	if err != nil {
+		return "", fmt.Errorf("node version not found: %w", err)
+	}
+	// the cypress package of the project is only installed when it matches the one of the image
+	return depscache.Key(lockfile, strings.TrimSpace(string(output)), framework.Name(), pm.Name, runner.PathCypressVersion()), nil
+}
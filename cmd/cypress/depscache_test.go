@@ -0,0 +1,121 @@
+// Package cypress assemble all commands required to run cypress unit testing
+package cypress
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Lord-Y/cypress-parallel-cli/executor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun_depsCache(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	repository := newRepository(t, map[string]string{
+		"package.json":            `{"dependencies": {"lodash": "4.17.21"}}`,
+		"package-lock.json":       `{"lockfileVersion": 3}`,
+		"cypress/e2e/login.cy.js": "",
+	})
+	cacheDir := t.TempDir()
+
+	tests := []struct {
+		node     string
+		expected []string
+		status   string
+	}{
+		{
+			node:     "v18.12.0",
+			expected: []string{"node --version", "npm ci", "npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome", "cypress --version"},
+			status:   DepsCacheMiss,
+		},
+		{
+			node:     "v18.12.0",
+			expected: []string{"node --version", "cypress --version"},
+			status:   DepsCacheHit,
+		},
+		{
+			node:     "v20.9.0",
+			expected: []string{"node --version", "npm ci", "npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome", "cypress --version"},
+			status:   DepsCacheMiss,
+		},
+	}
+
+	for _, tc := range tests {
+		node := tc.node
+		var installed []string
+		r := fakeCypress()
+		handler := r.Handler
+		r.Handler = func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+			switch cmd.Name {
+			case "node":
+				return []byte(node + "\n"), nil
+			case "npm":
+				dir := filepath.Join(cmd.Dir, "node_modules", "lodash")
+				if cmd.Args[0] == "install" {
+					dir = filepath.Join(cmd.Dir, ".cypress-parallel-cli", "node_modules", "mochawesome")
+				}
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					return nil, err
+				}
+				return nil, os.WriteFile(filepath.Join(dir, "package.json"), []byte("{}"), 0644)
+			case "cypress":
+				if cmd.Args[0] == "run" {
+					for _, dir := range []string{"node_modules/lodash", ".cypress-parallel-cli/node_modules/mochawesome"} {
+						if _, err := os.Stat(filepath.Join(cmd.Dir, dir, "package.json")); err == nil {
+							installed = append(installed, dir)
+						}
+					}
+				}
+			}
+			return handler(ctx, cmd)
+		}
+
+		var c Cypress
+		c.Repository = repository
+		c.Specs = "cypress/e2e/login.cy.js"
+		c.UniqID = "uid"
+		c.Browser = "chrome"
+		c.Timeout = timeout
+		c.Executor = r
+		c.DepsCacheDir = cacheDir
+
+		assert.NoError(c.Run())
+		assert.Equal(tc.status, c.depsCache.status)
+		assert.Equal(tc.expected, commandLines(r.Commands())[:len(tc.expected)])
+		assert.Equal([]string{"node_modules/lodash", ".cypress-parallel-cli/node_modules/mochawesome"}, installed)
+	}
+
+	entries, err := filepath.Glob(filepath.Join(cacheDir, "*", "entry.json"))
+	assert.NoError(err)
+	assert.Len(entries, 2)
+}
+
+func TestRun_depsCache_withoutLockfile(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	r := fakeCypress()
+	var c Cypress
+	c.Repository = newRepository(t, map[string]string{
+		"package.json":            `{}`,
+		"cypress/e2e/login.cy.js": "",
+	})
+	c.Specs = "cypress/e2e/login.cy.js"
+	c.UniqID = "uid"
+	c.Timeout = timeout
+	c.Executor = r
+	c.DepsCacheDir = t.TempDir()
+
+	assert.NoError(c.Run())
+	assert.Equal("", c.depsCache.status)
+	assert.Equal([]string{"npm install", "npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome"}, commandLines(r.Commands())[:2])
+	entries, err := os.ReadDir(c.DepsCacheDir)
+	assert.NoError(err)
+	assert.Empty(entries)
+}
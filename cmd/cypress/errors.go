@@ -0,0 +1,66 @@
+// Package cypress assemble all commands required to run cypress unit testing
+package cypress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Exit codes of the program, ordered from the least to the most severe
+const (
+	ExitSuccess        = 0 // all specs passed
+	ExitTestFailure    = 1 // at least one spec failed
+	ExitInfrastructure = 2 // clone, install or any other requirement failed
+	ExitTimeout        = 3 // timeout reached before the end of the execution
+	ExitCancelled      = 4 // execution has been cancelled
+)
+
+// RunError is returned by Run when the execution is not successful
+type RunError struct {
+	Code int   // Exit code matching the failure
+	Err  error // Error that caused the failure
+}
+
+// Error returns the error message
+func (e *RunError) Error() string {
+	return fmt.Sprintf("exit code %d: %s", e.Code, e.Err)
+}
+
+// Unwrap returns the error that caused the failure
+func (e *RunError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code to use for the given error.
+// Errors that are not a RunError, like command line usage errors, return 1
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitSuccess
+	}
+	var e *RunError
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ExitTestFailure
+}
+
+// newRunError returns a RunError with the given code unless the context
+// is already done in which case the timeout or cancelled code is used
+func newRunError(ctx context.Context, code int, err error) *RunError {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		code = ExitTimeout
+	case context.Canceled:
+		code = ExitCancelled
+	}
+	return &RunError{Code: code, Err: err}
+}
+
+// worse returns the most severe exit code
+func worse(a, b int) int {
+	if b > a {
+		return b
+	}
+	return a
+}
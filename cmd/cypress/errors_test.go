@@ -0,0 +1,59 @@
+// Package cypress assemble all commands required to run cypress unit testing
+package cypress
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{
+			err:      nil,
+			expected: ExitSuccess,
+		},
+		{
+			err:      fmt.Errorf("usage error"),
+			expected: ExitTestFailure,
+		},
+		{
+			err:      &RunError{Code: ExitInfrastructure, Err: fmt.Errorf("clone failed")},
+			expected: ExitInfrastructure,
+		},
+		{
+			err:      fmt.Errorf("wrapped: %w", &RunError{Code: ExitTimeout, Err: context.DeadlineExceeded}),
+			expected: ExitTimeout,
+		},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(tc.expected, ExitCode(tc.err))
+	}
+}
+
+func TestNewRunError(t *testing.T) {
+	assert := assert.New(t)
+
+	err := newRunError(context.Background(), ExitInfrastructure, fmt.Errorf("npm install failed"))
+	assert.Equal(ExitInfrastructure, err.Code)
+	assert.Equal("exit code 2: npm install failed", err.Error())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(ExitCancelled, newRunError(ctx, ExitInfrastructure, ctx.Err()).Code)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	assert.Equal(ExitTimeout, newRunError(ctx, ExitInfrastructure, ctx.Err()).Code)
+
+	assert.Equal(ExitTimeout, worse(ExitTestFailure, ExitTimeout))
+	assert.Equal(ExitInfrastructure, worse(ExitInfrastructure, ExitSuccess))
+}
@@ -0,0 +1,76 @@
+// Package cypress assemble all commands required to run cypress unit testing
+package cypress
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Lord-Y/cypress-parallel-cli/htmlreport"
+	"github.com/Lord-Y/cypress-parallel-cli/mochawesome"
+	"github.com/rs/zerolog/log"
+)
+
+// htmlSpec is the html report of a unit with its parsed mochawesome report
+type htmlSpec struct {
+	spec   htmlreport.Spec
+	report mochawesome.Report
+}
+
+// recordHTML adds the mochawesome report of the unit to the ones merged in HTMLReport.
+// Results which are not mochawesome reports only show the status of the spec
+func (c *Cypress) recordHTML(err error, u unit, r specReport) {
+	if c.HTMLReport == "" {
+		return
+	}
+	z := htmlSpec{
+		spec: htmlreport.Spec{
+			Name:     u.spec,
+			Browser:  u.browser,
+			Status:   r.status,
+			Duration: r.duration,
+		},
+	}
+	if err != nil {
+		z.spec.Error = err.Error()
+	}
+	if r.content != nil {
+		if report, err := mochawesome.Parse(r.content); err == nil {
+			z.report = report
+			z.spec.Suites = report.Results
+		}
+	}
+
+	c.mu.Lock()
+	c.pages = append(c.pages, z)
+	c.mu.Unlock()
+}
+
+// writeHTML merges the recorded reports and writes them in HTMLReport sorted by spec and browser
+func (c *Cypress) writeHTML() {
+	if c.HTMLReport == "" || c.DryRun {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sort.SliceStable(c.pages, func(i, j int) bool {
+		if c.pages[i].spec.Name != c.pages[j].spec.Name {
+			return c.pages[i].spec.Name < c.pages[j].spec.Name
+		}
+		return c.pages[i].spec.Browser < c.pages[j].spec.Browser
+	})
+	p := htmlreport.Page{
+		Title:     "cypress-parallel-cli " + c.UniqID,
+		Generated: time.Now(),
+	}
+	reports := make([]mochawesome.Report, len(c.pages))
+	for i, page := range c.pages {
+		reports[i] = page.report
+		p.Specs = append(p.Specs, page.spec)
+	}
+	p.Stats = mochawesome.Merge(reports...).Stats
+	if err := htmlreport.Write(c.HTMLReport, p); err != nil {
+		log.Error().Err(err).Msgf("Error occured while writing html report to %s", c.HTMLReport)
+		return
+	}
+	log.Debug().Msgf("Html report written to %s", c.HTMLReport)
+}
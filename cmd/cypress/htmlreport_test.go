@@ -0,0 +1,73 @@
+// Package cypress assemble all commands required to run cypress unit testing
+package cypress
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordHTML(t *testing.T) {
+	assert := assert.New(t)
+	c := Cypress{HTMLReport: "index.html"}
+	c.recordHTML(nil, unit{spec: "cypress/e2e/login.cy.js", browser: "firefox"}, specReport{
+		status:   StatusFailed,
+		duration: 2500,
+		content:  []byte(`{"stats":{"tests":2,"passes":1,"failures":1,"duration":1500},"results":[{"file":"cypress/e2e/login.cy.js","tests":[{"title":"logs in","pass":true},{"title":"fails","fail":true,"err":{"message":"expected true"}}]}]}`),
+	})
+	c.recordHTML(fmt.Errorf("spec timeout reached after 5m0s"), unit{spec: "cypress/e2e/login.cy.js", browser: "chrome"}, specReport{status: StatusTimeout, duration: 300000})
+	c.recordHTML(nil, unit{spec: "cypress/e2e/admin.cy.js", browser: "chrome"}, specReport{status: StatusDone, content: []byte("not json")})
+
+	assert.Len(c.pages, 3)
+	assert.Equal(2, c.pages[0].report.Stats.Tests)
+	assert.Len(c.pages[0].spec.Suites, 1)
+	assert.Equal("spec timeout reached after 5m0s", c.pages[1].spec.Error)
+	assert.Nil(c.pages[2].spec.Suites)
+
+	c.HTMLReport = filepath.Join(t.TempDir(), "reports", "index.html")
+	c.writeHTML()
+	content, err := os.ReadFile(c.HTMLReport)
+	assert.NoError(err)
+	z := string(content)
+	assert.Contains(z, "<tr><td>3</td><td>1</td><td>0</td><td>1</td><td>1</td><td>0</td><td>0</td></tr>")
+	assert.Contains(z, "<tr><td>2</td><td>1</td><td>1</td><td>0</td><td>0</td><td>1.5s</td></tr>")
+	assert.Contains(z, "expected true")
+	// specs are sorted by name and browser
+	admin := strings.Index(z, "cypress/e2e/admin.cy.js in chrome")
+	chrome := strings.Index(z, "cypress/e2e/login.cy.js in chrome")
+	firefox := strings.Index(z, "cypress/e2e/login.cy.js in firefox")
+	assert.True(admin > 0 && admin < chrome && chrome < firefox)
+}
+
+func TestRun_htmlReport(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	output := filepath.Join(t.TempDir(), "reports", "index.html")
+	var c Cypress
+	c.Repository = newRepository(t, map[string]string{
+		"package.json":        `{}`,
+		"cypress/e2e/a.cy.js": "",
+		"cypress/e2e/b.cy.js": "",
+	})
+	c.Specs = "cypress/e2e"
+	c.UniqID = "uid"
+	c.Browser = "chrome"
+	c.Timeout = timeout
+	c.Executor = fakeCypress("cypress/e2e/b.cy.js")
+	c.HTMLReport = output
+
+	assert.Error(c.Run())
+	content, err := os.ReadFile(output)
+	assert.NoError(err)
+	z := string(content)
+	assert.Contains(z, "<title>cypress-parallel-cli uid</title>")
+	assert.Contains(z, "<tr><td>2</td><td>1</td><td>0</td><td>1</td><td>0</td><td>0</td><td>0</td></tr>")
+	assert.Contains(z, "cypress/e2e/a.cy.js in chrome")
+	assert.Contains(z, `<span class="state">FAILED</span> cypress/e2e/b.cy.js in chrome`)
+}
@@ -0,0 +1,84 @@
+// Package cypress assemble all commands required to run cypress unit testing
+package cypress
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Lord-Y/cypress-parallel-cli/junit"
+	"github.com/Lord-Y/cypress-parallel-cli/mochawesome"
+	"github.com/rs/zerolog/log"
+)
+
+// recordJUnit adds the testsuite of the unit to the ones written in JUnitOutput.
+// Each test is a testcase, errors preventing the spec to run like clone failures
+// or timeouts are errored testcases named after the spec
+func (c *Cypress) recordJUnit(err error, u unit, r specReport) {
+	if c.JUnitOutput == "" {
+		return
+	}
+	name := u.spec
+	if len(c.browsers()) > 1 {
+		name = u.String()
+	}
+	s := junit.NewTestsuite(name, time.Duration(r.duration)*time.Millisecond)
+	if u.browser != "" {
+		s.AddProperty("browser", u.browser)
+	}
+	s.AddProperty("status", r.status)
+
+	for _, test := range r.tests {
+		tc := junit.Testcase{
+			Name:      test.Title,
+			Classname: name,
+			Time:      junit.Seconds(time.Duration(test.Duration) * time.Millisecond),
+		}
+		switch test.State {
+		case mochawesome.Failed:
+			tc.Failure = &junit.Result{Message: test.Error, Text: test.Stack}
+			if test.Stack == "" {
+				tc.Failure.Text = test.Error
+			}
+		case mochawesome.Pending, mochawesome.Skipped:
+			tc.Skipped = &junit.Result{Message: test.State}
+		}
+		s.Add(tc)
+	}
+
+	tc := junit.Testcase{Name: name, Classname: name, Time: s.Time}
+	switch {
+	case r.status == StatusSkipped:
+		tc.Skipped = &junit.Result{Message: "skipped after other failures"}
+	case err != nil:
+		tc.Error = &junit.Result{Message: err.Error(), Type: r.status}
+	case len(r.tests) > 0:
+		tc = junit.Testcase{}
+	case r.status == StatusFailed:
+		tc.Failure = &junit.Result{Message: fmt.Sprintf("spec %s failed", u)}
+	}
+	if tc.Name != "" {
+		s.Add(tc)
+	}
+
+	c.mu.Lock()
+	c.suites = append(c.suites, s)
+	c.mu.Unlock()
+}
+
+// writeJUnit writes the recorded testsuites in JUnitOutput sorted by name
+func (c *Cypress) writeJUnit() {
+	if c.JUnitOutput == "" || c.DryRun {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sort.SliceStable(c.suites, func(i, j int) bool {
+		return c.suites[i].Name < c.suites[j].Name
+	})
+	if err := junit.Write(c.JUnitOutput, c.UniqID, c.suites); err != nil {
+		log.Error().Err(err).Msgf("Error occured while writing JUnit report to %s", c.JUnitOutput)
+		return
+	}
+	log.Debug().Msgf("JUnit report written to %s", c.JUnitOutput)
+}
@@ -0,0 +1,95 @@
+// Package cypress assemble all commands required to run cypress unit testing
+package cypress
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Lord-Y/cypress-parallel-cli/junit"
+	"github.com/Lord-Y/cypress-parallel-cli/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordJUnit(t *testing.T) {
+	assert := assert.New(t)
+	c := Cypress{Browser: "chrome,firefox", JUnitOutput: "junit.xml"}
+	c.recordJUnit(nil, unit{spec: "cypress/e2e/login.cy.js", browser: "chrome"}, specReport{
+		status:   StatusFailed,
+		duration: 1500,
+		stats:    &runner.Stats{Tests: 3, Passes: 1, Failures: 1, Pending: 1},
+		tests: []runner.Test{
+			{Title: "login logs in", State: "passed", Duration: 1200},
+			{Title: "login fails", State: "failed", Duration: 300, Error: "AssertionError: expected .error", Stack: "AssertionError\n    at login.cy.js:3:9"},
+			{Title: "login remembers me", State: "pending"},
+		},
+	})
+	c.recordJUnit(fmt.Errorf("spec timeout reached after 5m0s"), unit{spec: "cypress/e2e/users.cy.js", browser: "firefox"}, specReport{status: StatusTimeout, duration: 300000})
+	c.recordJUnit(nil, unit{spec: "cypress/e2e/admin.cy.js", browser: "chrome"}, specReport{status: StatusSkipped})
+	c.recordJUnit(nil, unit{spec: "cypress/e2e/admin.cy.js", browser: "firefox"}, specReport{status: StatusFailed})
+
+	assert.Len(c.suites, 4)
+	login := c.suites[0]
+	assert.Equal("cypress/e2e/login.cy.js in chrome", login.Name)
+	assert.Equal("1.500", login.Time)
+	assert.Equal([]junit.Property{{Name: "browser", Value: "chrome"}, {Name: "status", Value: StatusFailed}}, login.Properties.Properties)
+	assert.Equal([]int{3, 1, 0, 1}, []int{login.Tests, login.Failures, login.Errors, login.Skipped})
+	assert.Equal(&junit.Result{Message: "AssertionError: expected .error", Text: "AssertionError\n    at login.cy.js:3:9"}, login.Testcases[1].Failure)
+
+	users := c.suites[1]
+	assert.Equal([]int{1, 0, 1, 0}, []int{users.Tests, users.Failures, users.Errors, users.Skipped})
+	assert.Equal(&junit.Result{Message: "spec timeout reached after 5m0s", Type: StatusTimeout}, users.Testcases[0].Error)
+
+	assert.NotNil(c.suites[2].Testcases[0].Skipped)
+	assert.NotNil(c.suites[3].Testcases[0].Failure)
+
+	c.JUnitOutput = ""
+	c.recordJUnit(nil, unit{spec: "cypress/e2e/login.cy.js"}, specReport{status: StatusDone})
+	assert.Len(c.suites, 4)
+}
+
+func TestRun_junit(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	tests := []struct {
+		repository string
+		expected   junit.Testsuites
+	}{
+		{
+			repository: newRepository(t, map[string]string{
+				"package.json":        `{}`,
+				"cypress/e2e/a.cy.js": "",
+				"cypress/e2e/b.cy.js": "",
+			}),
+			expected: junit.Testsuites{Tests: 2, Failures: 1},
+		},
+		{
+			repository: filepath.Join(t.TempDir(), "missing"),
+			expected:   junit.Testsuites{Tests: 1, Errors: 1},
+		},
+	}
+
+	for _, tc := range tests {
+		output := filepath.Join(t.TempDir(), "reports", "junit.xml")
+		var c Cypress
+		c.Repository = tc.repository
+		c.Specs = "cypress/e2e"
+		c.UniqID = "uid"
+		c.Browser = "chrome"
+		c.Timeout = timeout
+		c.Executor = fakeCypress("cypress/e2e/b.cy.js")
+		c.JUnitOutput = output
+
+		assert.Error(c.Run())
+		content, err := os.ReadFile(output)
+		assert.NoError(err)
+		var z junit.Testsuites
+		assert.NoError(xml.Unmarshal(content, &z))
+		assert.Equal("uid", z.Name)
+		assert.Equal([]int{tc.expected.Tests, tc.expected.Failures, tc.expected.Errors}, []int{z.Tests, z.Failures, z.Errors})
+	}
+}
@@ -0,0 +1,163 @@
+// Package cypress assemble all commands required to run cypress unit testing
+package cypress
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Lord-Y/cypress-parallel-cli/executor"
+	"github.com/Lord-Y/cypress-parallel-cli/runner"
+	"github.com/Lord-Y/cypress-parallel-cli/xvfb"
+	"github.com/rs/zerolog/log"
+)
+
+// Plan formats
+const (
+	PlanFormatText = "text"
+	PlanFormatJSON = "json"
+)
+
+// Plan is what Run would execute, printed by dry runs
+type Plan struct {
+	Runner         string          `json:"runner"`             // framework running specs
+	Version        runner.Version  `json:"version"`            // versions of the framework
+	PackageManager string          `json:"packageManager"`     // program installing dependencies
+	Specs          []string        `json:"specs"`              // specs to run
+	Browsers       []string        `json:"browsers,omitempty"` // browsers in which each spec is run, framework default when empty
+	Workers        int             `json:"workers"`            // number of specs run at the same time
+	Install        []string        `json:"install"`            // commands installing dependencies
+	Executions     []PlanExecution `json:"executions"`         // command run for each spec in each browser
+}
+
+// PlanExecution is the expected execution of a spec in a browser
+type PlanExecution struct {
+	Spec    string   `json:"spec"`              // spec to run
+	Browser string   `json:"browser,omitempty"` // browser to use, framework default when empty
+	Worker  int      `json:"worker"`            // worker expected to run the spec
+	Display string   `json:"display,omitempty"` // Xvfb display of the worker, empty when not required
+	Command string   `json:"command"`           // command line running the spec
+	Env     []string `json:"env,omitempty"`     // environment variables of the command
+	Timeout string   `json:"timeout,omitempty"` // maximum duration of each attempt, empty when unlimited
+}
+
+// dryRun records the commands instead of running them. Probes inspecting the host are run
+// with Executor so the recorded install matches the one of a real run
+type dryRun struct {
+	executor.Executor
+	recorder *executor.Recorder
+}
+
+// Start runs probes and records the other commands
+func (d dryRun) Start(ctx context.Context, cmd executor.Command) (executor.Process, error) {
+	if cmd.Probe {
+		return d.Executor.Start(ctx, cmd)
+	}
+	return d.recorder.Start(ctx, cmd)
+}
+
+// plan returns what Run would execute in gitdir without installing anything.
+// Install commands are recorded instead of being run, probes are run
+func (c *Cypress) plan(ctx context.Context, gitdir string) (z Plan, err error) {
+	framework := c.runner()
+	z.Runner = framework.Name()
+	z.Specs = c.specs
+	for _, browser := range c.browsers() {
+		if browser != "" {
+			z.Browsers = append(z.Browsers, browser)
+		}
+	}
+	units := c.units()
+	z.Workers = c.maxParallel(len(units))
+
+	pm, err := runner.DetectPackageManager(gitdir)
+	if err != nil {
+		return
+	}
+	z.PackageManager = pm.Name
+	recorder := &executor.Recorder{}
+	if err = framework.Install(ctx, dryRun{Executor: c.executor(), recorder: recorder}, gitdir); err != nil {
+		return
+	}
+	for _, cmd := range recorder.Commands() {
+		z.Install = append(z.Install, cmd.String())
+	}
+
+	// the version may only be available once dependencies are installed
+	z.Version, err = framework.Version(ctx, c.executor(), gitdir)
+	if err != nil {
+		log.Warn().Err(err).Msgf("%s version cannot be detected before installing dependencies", framework.Name())
+		err = nil
+	}
+
+	var displays []string
+	if c.display(gitdir) {
+		displays = xvfb.Free(z.Workers)
+	}
+	for i, u := range units {
+		worker := i % z.Workers
+		x := c.execution(gitdir, z.Version.String(), u, c.reportFilename(u))
+		var cmd executor.Command
+		cmd, err = framework.Command(x)
+		if err != nil {
+			return
+		}
+		e := PlanExecution{
+			Spec:    u.spec,
+			Browser: u.browser,
+			Worker:  worker + 1,
+			Command: cmd.String(),
+			Env:     cmd.Env,
+		}
+		if timeout := c.specTimeout(u.spec); timeout > 0 {
+			e.Timeout = timeout.String()
+		}
+		if worker < len(displays) {
+			e.Display = displays[worker]
+			e.Env = append(e.Env, fmt.Sprintf("DISPLAY=%s", e.Display))
+		}
+		z.Executions = append(z.Executions, e)
+	}
+	return
+}
+
+// Print writes the plan in the given format
+func (p Plan) Print(w io.Writer, format string) error {
+	switch format {
+	case PlanFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(p)
+	case PlanFormatText, "":
+	default:
+		return fmt.Errorf("unknown plan format %s", format)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Runner: %s %s\n", p.Runner, p.Version)
+	fmt.Fprintf(&b, "Package manager: %s\n", p.PackageManager)
+	fmt.Fprintf(&b, "Specs: %d\n", len(p.Specs))
+	if len(p.Browsers) > 0 {
+		fmt.Fprintf(&b, "Browsers: %s\n", strings.Join(p.Browsers, ","))
+	}
+	fmt.Fprintf(&b, "Workers: %d\n", p.Workers)
+	fmt.Fprintf(&b, "Install:\n")
+	for _, cmd := range p.Install {
+		fmt.Fprintf(&b, "  %s\n", cmd)
+	}
+	fmt.Fprintf(&b, "Executions:\n")
+	for _, e := range p.Executions {
+		fmt.Fprintf(&b, "  worker %d", e.Worker)
+		if e.Display != "" {
+			fmt.Fprintf(&b, " display %s", e.Display)
+		}
+		if e.Timeout != "" {
+			fmt.Fprintf(&b, " timeout %s", e.Timeout)
+		}
+		fmt.Fprintf(&b, ": %s\n", e.Command)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,162 @@
+// Package cypress assemble all commands required to run cypress unit testing
+package cypress
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Lord-Y/cypress-parallel-cli/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun_dryRun(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	var reports int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reports, 1)
+	}))
+	defer ts.Close()
+
+	repository := newRepository(t, map[string]string{
+		"package.json":                  `{"devDependencies": {"cypress": "10.10.0"}}`,
+		"cypress/e2e/login.cy.js":       "",
+		"cypress/e2e/admin/users.cy.js": "",
+	})
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: PlanFormatText,
+			expected: `Runner: cypress 10.10.0
+Package manager: npm
+Specs: 2
+Browsers: chrome
+Workers: 2
+Install:
+  npm install
+  npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome
+Executions:
+  worker 1: cypress run --browser chrome --spec cypress/e2e/admin/users.cy.js --reporter ./.cypress-parallel-cli/node_modules/mochawesome --reporter-options reportFilename=cypress_e2e_admin_users
+  worker 2: cypress run --browser chrome --spec cypress/e2e/login.cy.js --reporter ./.cypress-parallel-cli/node_modules/mochawesome --reporter-options reportFilename=cypress_e2e_login
+`,
+		},
+		{
+			format: PlanFormatJSON,
+		},
+	}
+
+	for _, tc := range tests {
+		r := fakeCypress()
+		out := new(bytes.Buffer)
+		var c Cypress
+		c.ApiURL = ts.URL
+		c.ReportBack = true
+		c.Repository = repository
+		c.Specs = "cypress/e2e"
+		c.UniqID = "uid"
+		c.Browser = "chrome"
+		c.Timeout = timeout
+		c.MaxParallel = 2
+		c.Executor = r
+		c.DryRun = true
+		c.PlanFormat = tc.format
+		c.stdout = out
+
+		assert.NoError(c.Run())
+		assert.Equal([]string{"cypress --version"}, commandLines(r.Commands()))
+		if tc.format == PlanFormatJSON {
+			var plan Plan
+			assert.NoError(json.Unmarshal(out.Bytes(), &plan))
+			assert.Equal("10.10.0", plan.Version.Package)
+			assert.Equal([]string{"npm install", "npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome"}, plan.Install)
+			assert.Len(plan.Executions, 2)
+			assert.Equal("cypress/e2e/login.cy.js", plan.Executions[1].Spec)
+			assert.Equal(2, plan.Executions[1].Worker)
+			assert.Equal([]string{"NO_COLOR=1"}, plan.Executions[1].Env)
+		} else {
+			assert.Equal(tc.expected, out.String())
+		}
+	}
+	assert.Equal(int32(0), atomic.LoadInt32(&reports))
+}
+
+func TestRun_dryRun_cypressWrapper(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+	// a cypress which is not an npm package is probed with cypress --version
+	if err := os.WriteFile(filepath.Join(os.Getenv("PATH"), "cypress"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	repository := newRepository(t, map[string]string{
+		"package.json":            `{"packageManager": "yarn@1.22.19", "devDependencies": {"cypress": "^10.0.0"}}`,
+		"cypress/e2e/login.cy.js": "",
+	})
+
+	out := new(bytes.Buffer)
+	var c Cypress
+	c.Repository = repository
+	c.Specs = "cypress/e2e"
+	c.UniqID = "uid"
+	c.Timeout = timeout
+	c.Executor = fakeCypress()
+	c.DryRun = true
+	c.PlanFormat = PlanFormatJSON
+	c.stdout = out
+
+	assert.NoError(c.Run())
+	var plan Plan
+	assert.NoError(json.Unmarshal(out.Bytes(), &plan))
+	assert.Equal("yarn", plan.PackageManager)
+	// cypress 10.10.0 of the image satisfies ^10.0.0 so the project package is kept
+	assert.Equal([]string{"yarn install", "npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome"}, plan.Install)
+}
+
+func TestRun_dryRun_format(t *testing.T) {
+	assert := assert.New(t)
+	var c Cypress
+	c.Specs = "cypress/e2e/login.cy.js"
+	c.DryRun = true
+	c.PlanFormat = "yaml"
+
+	err := c.Run()
+	assert.EqualError(err, "unknown plan format yaml")
+	assert.Equal(ExitTestFailure, ExitCode(err))
+}
+
+func TestPlan_print(t *testing.T) {
+	assert := assert.New(t)
+	p := Plan{
+		Runner:         "cypress",
+		Version:        runner.Version{Package: "10.10.0"},
+		PackageManager: "npm",
+		Specs:          []string{"cypress/e2e/login.cy.js"},
+		Workers:        1,
+		Install:        []string{"npm install"},
+		Executions: []PlanExecution{
+			{
+				Spec:    "cypress/e2e/login.cy.js",
+				Worker:  1,
+				Display: ":99",
+				Command: "cypress run --spec cypress/e2e/login.cy.js",
+				Timeout: "5m0s",
+			},
+		},
+	}
+	out := new(bytes.Buffer)
+	assert.NoError(p.Print(out, ""))
+	assert.Contains(out.String(), "  worker 1 display :99 timeout 5m0s: cypress run --spec cypress/e2e/login.cy.js\n")
+	assert.Error(p.Print(out, "yaml"))
+}
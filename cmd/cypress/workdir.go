@@ -0,0 +1,137 @@
+// Package cypress assemble all commands required to run cypress unit testing
+package cypress
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Lord-Y/cypress-parallel-cli/git"
+	"github.com/rs/zerolog/log"
+)
+
+// workdir returns the absolute path of the local directory of the project, set by Workdir
+// or by a file:// Repository, empty when the repository must be cloned
+func (c *Cypress) workdir() (string, error) {
+	dir := c.Workdir
+	if dir == "" && strings.HasPrefix(c.Repository, "file://") {
+		u, err := url.Parse(c.Repository)
+		if err != nil {
+			return "", err
+		}
+		dir = u.Path
+	}
+	if dir == "" {
+		return "", nil
+	}
+	return filepath.Abs(dir)
+}
+
+// checkout returns the directory in which specs are run and a function removing it once the execution is done.
+// The repository is cloned in a temporary directory unless a workdir is set, which is copied in a
+// temporary directory without its node_modules or used as is with InPlace. The workdir is never removed
+func (c *Cypress) checkout() (dir string, cleanup func(), err error) {
+	cleanup = func() {}
+	if c.Workdir == "" {
+		gc := git.Repository{
+			Repository: c.Repository,
+			Username:   c.Username,
+			Password:   c.Password,
+			Ref:        c.Branch,
+		}
+		if dir, err = gc.Clone(); err != nil {
+			log.Error().Err(err).Msg("Error occured while cloning git repository")
+			if dir != "" {
+				os.RemoveAll(dir)
+			}
+			return "", cleanup, err
+		}
+		return dir, func() { os.RemoveAll(dir) }, nil
+	}
+
+	info, err := os.Stat(c.Workdir)
+	if err == nil && !info.IsDir() {
+		err = fmt.Errorf("workdir %s is not a directory", c.Workdir)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Error occured while checking workdir")
+		return "", cleanup, err
+	}
+	if c.Branch != "" {
+		log.Warn().Msgf("Branch %s is ignored, specs are run from workdir %s", c.Branch, c.Workdir)
+	}
+	if c.InPlace {
+		if !c.DryRun {
+			log.Warn().Msgf("Running specs in place in workdir %s, its node_modules is reinstalled and its package.json may be rewritten", c.Workdir)
+		}
+		return c.Workdir, cleanup, nil
+	}
+
+	if dir, err = os.MkdirTemp("", "cypress-parallel-cli"); err != nil {
+		log.Error().Err(err).Msg("Error occured while creating scratch dir")
+		return "", cleanup, err
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+	if err = copyDir(c.Workdir, dir, "node_modules"); err != nil {
+		log.Error().Err(err).Msgf("Error occured while copying workdir %s", c.Workdir)
+		cleanup()
+		return "", func() {}, err
+	}
+	log.Debug().Msgf("Workdir %s copied in %s", c.Workdir, dir)
+	return dir, cleanup, nil
+}
+
+// copyDir copies the files of src in dst, skipping directories with the given names.
+// Symlinks are recreated as is
+func copyDir(src string, dst string, skip ...string) error {
+	return filepath.WalkDir(src, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			for _, name := range skip {
+				if d.Name() == name && file != src {
+					return filepath.SkipDir
+				}
+			}
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(file)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			in, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+			if err != nil {
+				return err
+			}
+			if _, err = io.Copy(out, in); err != nil {
+				out.Close()
+				return err
+			}
+			return out.Close()
+		}
+		return nil
+	})
+}
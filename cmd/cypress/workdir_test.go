@@ -0,0 +1,175 @@
+// Package cypress assemble all commands required to run cypress unit testing
+package cypress
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Lord-Y/cypress-parallel-cli/executor"
+	"github.com/stretchr/testify/assert"
+)
+
+// newWorkdir creates a local directory with the given files which is not a git repository
+func newWorkdir(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRun_workdir(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	tests := []struct {
+		fileURL bool
+		inPlace bool
+	}{
+		{},
+		{fileURL: true},
+		{inPlace: true},
+	}
+
+	for _, tc := range tests {
+		workdir := newWorkdir(t, map[string]string{
+			"package.json":                 `{}`,
+			"cypress/e2e/login.cy.js":      "",
+			"node_modules/lodash/index.js": "",
+		})
+		reportDir := filepath.Join(t.TempDir(), "reports")
+
+		var dirs []string
+		var nodeModules bool
+		r := fakeCypress()
+		handler := r.Handler
+		r.Handler = func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+			if cmd.Name == "cypress" && cmd.Args[0] == "run" {
+				dirs = append(dirs, cmd.Dir)
+				_, err := os.Stat(filepath.Join(cmd.Dir, "node_modules", "lodash", "index.js"))
+				nodeModules = err == nil
+			}
+			return handler(ctx, cmd)
+		}
+
+		var c Cypress
+		if tc.fileURL {
+			c.Repository = "file://" + workdir
+		} else {
+			c.Workdir = workdir
+		}
+		c.InPlace = tc.inPlace
+		c.ReportDir = reportDir
+		c.Specs = "cypress/e2e"
+		c.UniqID = "uid"
+		c.Browser = "chrome"
+		c.Timeout = timeout
+		c.Executor = r
+
+		assert.NoError(c.Run())
+		assert.Len(dirs, 1)
+		if tc.inPlace {
+			assert.Equal(workdir, dirs[0])
+			assert.True(nodeModules)
+		} else {
+			assert.NotEqual(workdir, dirs[0])
+			assert.False(nodeModules)
+			// the scratch copy is removed
+			_, err := os.Stat(dirs[0])
+			assert.True(os.IsNotExist(err))
+		}
+		// the workdir is never removed
+		for _, file := range []string{"package.json", "cypress/e2e/login.cy.js", "node_modules/lodash/index.js"} {
+			_, err := os.Stat(filepath.Join(workdir, file))
+			assert.NoError(err)
+		}
+		_, err := os.Stat(filepath.Join(reportDir, "cypress_e2e_login.json"))
+		assert.NoError(err)
+	}
+}
+
+func TestRun_workdir_invalid(t *testing.T) {
+	assert := assert.New(t)
+
+	var c Cypress
+	c.Specs = "cypress/e2e"
+	c.UniqID = "uid"
+	c.Timeout = timeout
+	c.Executor = fakeCypress()
+	err := c.Run()
+	assert.Error(err)
+	assert.Contains(err.Error(), "repository or workdir is required")
+
+	c.Workdir = filepath.Join(t.TempDir(), "missing")
+	assert.Equal(ExitInfrastructure, ExitCode(c.Run()))
+
+	file := filepath.Join(t.TempDir(), "package.json")
+	assert.NoError(os.WriteFile(file, []byte(`{}`), 0644))
+	c.Workdir = file
+	assert.Equal(ExitInfrastructure, ExitCode(c.Run()))
+	_, err = os.Stat(file)
+	assert.NoError(err)
+}
+
+func TestRun_workdir_staleReport(t *testing.T) {
+	assert := assert.New(t)
+	withoutCypress(t)
+	t.Setenv("DISPLAY", ":0")
+
+	tests := []struct {
+		reportDir bool
+	}{
+		{},
+		{reportDir: true},
+	}
+
+	for _, tc := range tests {
+		workdir := newWorkdir(t, map[string]string{
+			"package.json":            `{}`,
+			"cypress/e2e/login.cy.js": "",
+		})
+		reportDir := filepath.Join(workdir, "mochawesome-report")
+		if tc.reportDir {
+			reportDir = t.TempDir()
+		}
+		// report of a previous run which passed
+		report := filepath.Join(reportDir, "cypress_e2e_login.json")
+		assert.NoError(os.MkdirAll(reportDir, 0755))
+		assert.NoError(os.WriteFile(report, []byte(`{"stats": {"tests": 1, "passes": 1, "failures": 0}}`), 0644))
+
+		r := fakeCypress()
+		handler := r.Handler
+		r.Handler = func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+			if cmd.Name == "cypress" && cmd.Args[0] == "run" {
+				return nil, fmt.Errorf("browser crashed")
+			}
+			return handler(ctx, cmd)
+		}
+
+		var c Cypress
+		c.Workdir = workdir
+		c.InPlace = true
+		if tc.reportDir {
+			c.ReportDir = reportDir
+		}
+		c.Specs = "cypress/e2e"
+		c.UniqID = "uid"
+		c.Browser = "chrome"
+		c.Timeout = timeout
+		c.Executor = r
+
+		assert.NotEqual(ExitSuccess, ExitCode(c.Run()))
+		_, err := os.Stat(report)
+		assert.True(os.IsNotExist(err))
+	}
+}
@@ -0,0 +1,246 @@
+// Package cmd manage all commands required to launch cypress-parallel-cli
+package cmd
+
+import (
+	"time"
+
+	"github.com/Lord-Y/cypress-parallel-cli/cmd/cypress"
+	"github.com/urfave/cli/v2"
+)
+
+// runFlags returns the options shared by all frameworks
+func runFlags(z *cypress.Cypress) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "api-url",
+			Aliases:     []string{"a"},
+			Value:       "http://127.0.0.1:8080",
+			Usage:       "HTTP(s) api url of cypress-parallel",
+			Destination: &z.ApiURL,
+		},
+		&cli.StringFlag{
+			Name:        "repository",
+			Aliases:     []string{"r"},
+			Value:       "",
+			Usage:       "HTTP(s) git repository, or file:// url of a local directory used like --workdir (required unless --workdir is set)",
+			Destination: &z.Repository,
+		},
+		&cli.StringFlag{
+			Name:        "username",
+			Aliases:     []string{"u"},
+			Value:       "",
+			Usage:       "Username to used to fetch repository if required",
+			Destination: &z.Username,
+		},
+		&cli.StringFlag{
+			Name:        "password",
+			Aliases:     []string{"p"},
+			Value:       "",
+			Usage:       "Password to used to fetch repository if required",
+			Destination: &z.Password,
+		},
+		&cli.StringFlag{
+			Name:        "branch",
+			Aliases:     []string{"b"},
+			Value:       "",
+			Usage:       "Ref (Branch ex: master or tag ex: refs/tags/6.0.0) in which specs are hold (required)",
+			Destination: &z.Branch,
+		},
+		&cli.StringFlag{
+			Name:        "specs",
+			Aliases:     []string{"s"},
+			Required:    true,
+			Usage:       "Comma separated list of specs, directories or glob patterns like cypress/e2e/**/*.cy.{js,ts} (required)",
+			Destination: &z.Specs,
+		},
+		&cli.StringFlag{
+			Name:        "uniq-id",
+			Aliases:     []string{"uid"},
+			Required:    true,
+			Usage:       "Uniq ID to run cypress command (required)",
+			Destination: &z.UniqID,
+		},
+		&cli.BoolFlag{
+			Name:        "report-back",
+			Aliases:     []string{"rp"},
+			Usage:       "Send result to api",
+			Destination: &z.ReportBack,
+		},
+		&cli.IntFlag{
+			Name:        "timeout",
+			Aliases:     []string{"t"},
+			Value:       10,
+			Usage:       "Timeout after which the program will exit with error",
+			Destination: &z.Timeout,
+		},
+		&cli.IntFlag{
+			Name:        "max-parallel",
+			Aliases:     []string{"mp"},
+			Value:       0,
+			Usage:       "Maximum number of specs to run at the same time, computed from cpu and memory limits when 0",
+			Destination: &z.MaxParallel,
+		},
+		&cli.StringFlag{
+			Name:        "shard",
+			Aliases:     []string{"sh"},
+			Value:       "",
+			Usage:       "Portion of specs to run when the execution is split across several pods, formatted as index/total like 2/5",
+			Destination: &z.Shard,
+		},
+		&cli.IntFlag{
+			Name:        "retries",
+			Aliases:     []string{"re"},
+			Value:       0,
+			Usage:       "Number of times a failed spec is retried in a fresh process, specs passing after a retry are reported as FLAKY",
+			Destination: &z.Retries,
+		},
+		&cli.StringFlag{
+			Name:        "timings",
+			Aliases:     []string{"ti"},
+			Value:       "",
+			Usage:       "Path or HTTP(s) url of a json file with the last duration in milliseconds of each spec, used to balance shards",
+			Destination: &z.Timings,
+		},
+		&cli.StringFlag{
+			Name:        "timings-output",
+			Aliases:     []string{"to"},
+			Value:       "",
+			Usage:       "Path of the json file updated with the duration in milliseconds of each spec",
+			Destination: &z.TimingsOutput,
+		},
+		&cli.StringFlag{
+			Name:        "env",
+			Aliases:     []string{"en"},
+			Value:       "",
+			Usage:       "Comma separated list of key=value variables exposed to specs",
+			Destination: &z.Env,
+		},
+		&cli.BoolFlag{
+			Name:        "headed",
+			Aliases:     []string{"hd"},
+			Usage:       "Display the browser instead of running it headless",
+			Destination: &z.Headed,
+		},
+		&cli.BoolFlag{
+			Name:        "quiet",
+			Aliases:     []string{"q"},
+			Usage:       "Only print the output of the reporter",
+			Destination: &z.Quiet,
+		},
+		&cli.DurationFlag{
+			Name:        "spec-timeout",
+			Aliases:     []string{"st"},
+			Value:       0,
+			Usage:       "Maximum duration of each spec attempt like 90s or 5m after which only the spec is killed and reported as TIMEOUT, no limit when 0",
+			Destination: &z.SpecTimeout,
+		},
+		&cli.StringFlag{
+			Name:        "manifest",
+			Aliases:     []string{"mf"},
+			Value:       "",
+			Usage:       "Path of a json manifest overriding settings of some specs, relative to the repository root unless absolute",
+			Destination: &z.Manifest,
+		},
+		&cli.DurationFlag{
+			Name:        "grace-period",
+			Aliases:     []string{"gp"},
+			Value:       10 * time.Second,
+			Usage:       "Time given to running processes to exit after SIGTERM before being killed when the cli receives SIGTERM or SIGINT",
+			Destination: &z.GracePeriod,
+		},
+		&cli.BoolFlag{
+			Name:        "fail-fast",
+			Aliases:     []string{"ff"},
+			Usage:       "Cancel running specs and skip remaining ones after the first failed spec",
+			Destination: &z.FailFast,
+		},
+		&cli.IntFlag{
+			Name:        "max-failures",
+			Aliases:     []string{"mxf"},
+			Value:       0,
+			Usage:       "Number of failed specs after which running specs are cancelled and remaining ones skipped, never when 0",
+			Destination: &z.MaxFailures,
+		},
+		&cli.StringFlag{
+			Name:        "junit-output",
+			Aliases:     []string{"ju"},
+			Value:       "",
+			Usage:       "Path of the JUnit XML file written with the result of every spec once the execution is done",
+			Destination: &z.JUnitOutput,
+		},
+		&cli.StringFlag{
+			Name:        "html-report",
+			Aliases:     []string{"hr"},
+			Value:       "",
+			Usage:       "Path of the self-contained html page written with the merged mochawesome reports of every spec once the execution is done",
+			Destination: &z.HTMLReport,
+		},
+		&cli.StringFlag{
+			Name:        "deps-cache-dir",
+			Aliases:     []string{"dcd"},
+			Value:       "",
+			Usage:       "Directory shared between executions, like a mounted volume, in which dependencies installed from the same lockfile and node version are cached",
+			Destination: &z.DepsCacheDir,
+		},
+		&cli.StringFlag{
+			Name:        "artifacts",
+			Aliases:     []string{"art"},
+			Value:       "",
+			Usage:       "Where screenshots and videos of specs are uploaded, api or s3://bucket/prefix, not uploaded when empty",
+			Destination: &z.Artifacts,
+		},
+		&cli.StringFlag{
+			Name:        "artifacts-endpoint",
+			Aliases:     []string{"ae"},
+			Value:       "",
+			Usage:       "HTTP(s) url of the S3 compatible api like http://localhost:9000 for MinIO, AWS_ENDPOINT_URL or AWS when empty",
+			Destination: &z.ArtifactsEndpoint,
+		},
+		&cli.IntFlag{
+			Name:        "artifacts-max-size",
+			Aliases:     []string{"ams"},
+			Value:       0,
+			Usage:       "Maximum size in megabytes of the artifacts uploaded for each spec and browser, no limit when 0",
+			Destination: &z.ArtifactsMaxSize,
+		},
+		&cli.BoolFlag{
+			Name:        "artifacts-on-failure",
+			Aliases:     []string{"aof"},
+			Usage:       "Only upload artifacts of specs which are not DONE",
+			Destination: &z.ArtifactsOnFailure,
+		},
+		&cli.StringFlag{
+			Name:        "workdir",
+			Aliases:     []string{"wd"},
+			Value:       "",
+			Usage:       "Local directory of the project used instead of cloning the repository, copied in a scratch directory unless --in-place is set",
+			Destination: &z.Workdir,
+		},
+		&cli.BoolFlag{
+			Name:        "in-place",
+			Aliases:     []string{"ip"},
+			Usage:       "Run specs directly in the workdir instead of a scratch copy, node_modules is reinstalled and package.json may be rewritten when cypress conflicts",
+			Destination: &z.InPlace,
+		},
+		&cli.StringFlag{
+			Name:        "report-dir",
+			Aliases:     []string{"rd"},
+			Value:       "",
+			Usage:       "Directory in which the report of each spec is written, mochawesome-report of the project when empty",
+			Destination: &z.ReportDir,
+		},
+		&cli.BoolFlag{
+			Name:        "dry-run",
+			Aliases:     []string{"dr"},
+			Usage:       "Clone the repository, resolve specs and print the commands that would be run without installing or reporting anything",
+			Destination: &z.DryRun,
+		},
+		&cli.StringFlag{
+			Name:        "plan-format",
+			Aliases:     []string{"pf"},
+			Value:       "text",
+			Usage:       "Format of the plan printed by --dry-run, text or json",
+			Destination: &z.PlanFormat,
+		},
+	}
+}
@@ -0,0 +1,36 @@
+// Package cmd manage all commands required to launch cypress-parallel-cli
+package cmd
+
+import (
+	"github.com/Lord-Y/cypress-parallel-cli/runner"
+	"github.com/urfave/cli/v2"
+)
+
+// Playwright command options
+func Playwright(c *cli.Context) (z *cli.Command) {
+	return &cli.Command{
+		Name:  "playwright",
+		Usage: "options related to playwright command",
+		Flags: append(
+			runFlags(&playwright),
+			&cli.StringFlag{
+				Name:        "browser",
+				Aliases:     []string{"br"},
+				Value:       "",
+				Usage:       "Comma separated list of playwright projects to use to run unit testing, all projects when empty",
+				Destination: &playwright.Browser,
+			},
+			&cli.StringFlag{
+				Name:        "config-file",
+				Aliases:     []string{"cf"},
+				Value:       "",
+				Usage:       "Relative path of playwright config if not playwright.config.ts",
+				Destination: &playwright.ConfigFile,
+			},
+		),
+		Action: func(c *cli.Context) error {
+			playwright.Runner = runner.Playwright{}
+			return playwright.Run()
+		},
+	}
+}
@@ -8,10 +8,12 @@ import (
 // list of vars that will be use by cli
 var (
 	// cypress struct
-	cmd       cypress.Cypress
-	revision  string
-	buildDate string
-	goVersion string
+	cmd cypress.Cypress
+	// cypress struct running playwright
+	playwright cypress.Cypress
+	revision   string
+	buildDate  string
+	goVersion  string
 )
 
 var Version = "0.0.1"
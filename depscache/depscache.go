@@ -0,0 +1,210 @@
+// Package depscache caches the dependencies installed in a project in a directory shared between executions
+package depscache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// entryFile holds the description of an entry
+const entryFile = "entry.json"
+
+// Cache stores the installed dependencies of projects in Dir, one directory per key.
+// Entries are immutable once saved so they are restored without locking
+type Cache struct {
+	Dir string // Directory shared between executions, like a mounted volume
+}
+
+// Entry describes the dependencies saved under a key
+type Entry struct {
+	Dirs     []string      `json:"dirs"`     // Directories saved, relative to the project
+	Duration time.Duration `json:"duration"` // Time taken by the install restoring the entry saves
+}
+
+// Key returns the key of the dependencies installed from the lockfile with the given node version.
+// Extra values like the framework installing dependencies are part of the key
+func Key(lockfile []byte, node string, extra ...string) string {
+	h := sha256.New()
+	for _, value := range append([]string{node}, extra...) {
+		// values are length prefixed so they cannot be confused
+		fmt.Fprintf(h, "%d:%s\n", len(value), value)
+	}
+	h.Write(lockfile)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Restore restores the directories saved under key in dir.
+// Files are copied so the project cannot alter the cache, even when running as root.
+// It returns false without error when there is no entry, restored directories are removed on errors
+// along with the entry so the next install saves it again
+func (c Cache) Restore(key string, dir string) (z Entry, ok bool, err error) {
+	content, err := os.ReadFile(filepath.Join(c.Dir, key, entryFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return z, false, nil
+		}
+		return
+	}
+	if err = json.Unmarshal(content, &z); err != nil {
+		c.remove(key)
+		return z, false, fmt.Errorf("invalid cache entry %s: %w", key, err)
+	}
+	for _, d := range z.Dirs {
+		if err = os.RemoveAll(filepath.Join(dir, d)); err == nil {
+			err = copyTree(filepath.Join(c.Dir, key, d), filepath.Join(dir, d), false)
+		}
+		if err != nil {
+			// partially restored directories would be mistaken for installed ones
+			for _, d := range z.Dirs {
+				os.RemoveAll(filepath.Join(dir, d))
+			}
+			c.remove(key)
+			return z, false, err
+		}
+	}
+	return z, true, nil
+}
+
+// Save saves the directories of z in dir under key, they must all exist.
+// The entry is written in a temporary directory renamed once complete, while holding a lock
+// so concurrent executions do not save the same key. It returns false when the key is already saved
+func (c Cache) Save(key string, dir string, z Entry) (saved bool, err error) {
+	unlock, err := c.lock(key)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	final := filepath.Join(c.Dir, key)
+	if _, err = os.Stat(final); err == nil {
+		return false, nil
+	}
+	tmp, err := os.MkdirTemp(c.Dir, key+".tmp")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, d := range z.Dirs {
+		if err = copyTree(filepath.Join(dir, d), filepath.Join(tmp, d), true); err != nil {
+			return
+		}
+	}
+	content, err := json.Marshal(z)
+	if err != nil {
+		return
+	}
+	if err = os.WriteFile(filepath.Join(tmp, entryFile), content, 0644); err != nil {
+		return
+	}
+	if err = os.Chmod(tmp, 0755); err != nil {
+		return
+	}
+	if err = os.Rename(tmp, final); err != nil {
+		return
+	}
+	return true, nil
+}
+
+// remove removes the entry saved under key while holding its lock.
+// The entry is renamed first so concurrent restores never see it partially removed
+func (c Cache) remove(key string) error {
+	unlock, err := c.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	tmp, err := os.MkdirTemp(c.Dir, key+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	err = os.Rename(filepath.Join(c.Dir, key), filepath.Join(tmp, key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// lock holds the lock of key until unlock is called
+func (c Cache) lock(key string) (unlock func(), err error) {
+	if err = os.MkdirAll(c.Dir, 0755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(filepath.Join(c.Dir, key+".lock"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return
+	}
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// copyTree copies the files of src in dst, without write permission when readOnly is set.
+// Symlinks are recreated as is
+func copyTree(src string, dst string, readOnly bool) error {
+	return filepath.WalkDir(src, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(file)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			mode := info.Mode().Perm()
+			if readOnly {
+				return copyFile(file, target, mode&^0222)
+			}
+			return copyFile(file, target, mode|0200)
+		}
+		return nil
+	})
+}
+
+// copyFile copies src in dst with the given mode
+func copyFile(src string, dst string, mode fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,101 @@
+// Package depscache caches the dependencies installed in a project in a directory shared between executions
+package depscache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey(t *testing.T) {
+	assert := assert.New(t)
+	key := Key([]byte("lockfileVersion: 3"), "v18.12.0", "cypress", "npm")
+	assert.Len(key, 64)
+	assert.Equal(key, Key([]byte("lockfileVersion: 3"), "v18.12.0", "cypress", "npm"))
+	assert.NotEqual(key, Key([]byte("lockfileVersion: 2"), "v18.12.0", "cypress", "npm"))
+	assert.NotEqual(key, Key([]byte("lockfileVersion: 3"), "v20.9.0", "cypress", "npm"))
+	assert.NotEqual(key, Key([]byte("lockfileVersion: 3"), "v18.12.0", "playwright", "npm"))
+	assert.NotEqual(Key(nil, "a", "bc"), Key(nil, "ab", "c"))
+}
+
+func TestCache(t *testing.T) {
+	assert := assert.New(t)
+	cache := Cache{Dir: filepath.Join(t.TempDir(), "cache")}
+	project := t.TempDir()
+	for file, content := range map[string]string{
+		"node_modules/lodash/package.json":                    `{"version": "4.17.21"}`,
+		"node_modules/.bin/.keep":                             "",
+		".cypress-parallel-cli/node_modules/mochawesome/x.js": "module.exports = {}",
+	} {
+		assert.NoError(os.MkdirAll(filepath.Join(project, filepath.Dir(file)), 0755))
+		assert.NoError(os.WriteFile(filepath.Join(project, file), []byte(content), 0755))
+	}
+	assert.NoError(os.Symlink("../lodash/package.json", filepath.Join(project, "node_modules/.bin/lodash")))
+
+	_, ok, err := cache.Restore("key", project)
+	assert.NoError(err)
+	assert.False(ok)
+
+	_, err = cache.Save("key", project, Entry{Dirs: []string{"node_modules", "missing"}})
+	assert.Error(err)
+
+	entry := Entry{Dirs: []string{"node_modules", ".cypress-parallel-cli"}, Duration: time.Minute}
+	saved, err := cache.Save("key", project, entry)
+	assert.NoError(err)
+	assert.True(saved)
+	saved, err = cache.Save("key", project, entry)
+	assert.NoError(err)
+	assert.False(saved)
+
+	info, err := os.Stat(filepath.Join(cache.Dir, "key", "node_modules/lodash/package.json"))
+	assert.NoError(err)
+	assert.Equal(os.FileMode(0555), info.Mode().Perm())
+	// the project keeps writable files
+	info, err = os.Stat(filepath.Join(project, "node_modules/lodash/package.json"))
+	assert.NoError(err)
+	assert.Equal(os.FileMode(0755), info.Mode().Perm())
+
+	restored := t.TempDir()
+	assert.NoError(os.MkdirAll(filepath.Join(restored, "node_modules", "stale"), 0755))
+	z, ok, err := cache.Restore("key", restored)
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(entry, z)
+	content, err := os.ReadFile(filepath.Join(restored, "node_modules/.bin/lodash"))
+	assert.NoError(err)
+	assert.Equal(`{"version": "4.17.21"}`, string(content))
+	content, err = os.ReadFile(filepath.Join(restored, ".cypress-parallel-cli/node_modules/mochawesome/x.js"))
+	assert.NoError(err)
+	assert.Equal("module.exports = {}", string(content))
+	_, err = os.Stat(filepath.Join(restored, "node_modules", "stale"))
+	assert.True(os.IsNotExist(err))
+	// restored files are copies, writing them leaves the cache untouched
+	assert.NoError(os.WriteFile(filepath.Join(restored, "node_modules/lodash/package.json"), []byte("{}"), 0644))
+	content, err = os.ReadFile(filepath.Join(cache.Dir, "key", "node_modules/lodash/package.json"))
+	assert.NoError(err)
+	assert.Equal(`{"version": "4.17.21"}`, string(content))
+
+	// broken entries are removed so they are saved again
+	assert.NoError(os.RemoveAll(filepath.Join(cache.Dir, "key", ".cypress-parallel-cli")))
+	_, ok, err = cache.Restore("key", restored)
+	assert.Error(err)
+	assert.False(ok)
+	_, err = os.Stat(filepath.Join(restored, "node_modules"))
+	assert.True(os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(cache.Dir, "key"))
+	assert.True(os.IsNotExist(err))
+	saved, err = cache.Save("key", project, entry)
+	assert.NoError(err)
+	assert.True(saved)
+
+	assert.NoError(os.MkdirAll(filepath.Join(cache.Dir, "invalid"), 0755))
+	assert.NoError(os.WriteFile(filepath.Join(cache.Dir, "invalid", entryFile), []byte("{"), 0644))
+	_, ok, err = cache.Restore("invalid", restored)
+	assert.Error(err)
+	assert.False(ok)
+	_, err = os.Stat(filepath.Join(cache.Dir, "invalid"))
+	assert.True(os.IsNotExist(err))
+}
@@ -0,0 +1,124 @@
+// Package executor runs the external commands required by the cli
+package executor
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Command is an external command to run
+type Command struct {
+	Name string   // Program to run
+	Args []string // Arguments of the program
+	Dir  string   // Working directory, current directory when empty
+	Env  []string // Environment variables appended to the current environment
+
+	Probe bool // Whether the command only inspects the host, like printing a version, and changes nothing
+}
+
+// String returns the command line
+func (c Command) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
+
+// Process is a started command
+type Process interface {
+	// Wait waits for the process to exit and returns its standard output
+	Wait() ([]byte, error)
+	// Signal sends the signal to the process and all its children
+	Signal(sig syscall.Signal) error
+}
+
+// Executor starts commands.
+// Processes must be killed when the context is done
+type Executor interface {
+	Start(ctx context.Context, cmd Command) (Process, error)
+}
+
+// Output runs the command and returns its standard output
+func Output(ctx context.Context, e Executor, cmd Command) ([]byte, error) {
+	p, err := e.Start(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	return p.Wait()
+}
+
+// Local runs commands on the local host, each one in its own process group
+type Local struct {
+	// GracePeriod is the time given to processes to exit after SIGTERM before
+	// being killed with SIGKILL when the context is done, they are killed right away when 0
+	GracePeriod time.Duration
+}
+
+// localProcess is a process started by Local
+type localProcess struct {
+	cmd    *exec.Cmd
+	stdout *bytes.Buffer
+	stderr *bytes.Buffer
+	done   chan struct{}
+}
+
+// Start starts the command in its own process group
+// which is terminated when the context is done
+func (l Local) Start(ctx context.Context, c Command) (Process, error) {
+	cmd := exec.Command(c.Name, c.Args...)
+	cmd.Dir = c.Dir
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	p := &localProcess{
+		cmd:    cmd,
+		stdout: new(bytes.Buffer),
+		stderr: new(bytes.Buffer),
+		done:   make(chan struct{}),
+	}
+	cmd.Stdout = p.stdout
+	cmd.Stderr = p.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-p.done:
+		case <-ctx.Done():
+			p.terminate(l.GracePeriod)
+		}
+	}()
+	return p, nil
+}
+
+// Wait waits for the process to exit and returns its standard output
+func (p *localProcess) Wait() ([]byte, error) {
+	err := p.cmd.Wait()
+	close(p.done)
+	if e, ok := err.(*exec.ExitError); ok {
+		e.Stderr = p.stderr.Bytes()
+	}
+	return p.stdout.Bytes(), err
+}
+
+// terminate sends SIGTERM to the process group and SIGKILL once the grace period is over.
+// SIGKILL is always sent to kill children remaining after the process exited
+func (p *localProcess) terminate(grace time.Duration) {
+	if grace > 0 {
+		_ = p.Signal(syscall.SIGTERM)
+		select {
+		case <-p.done:
+		case <-time.After(grace):
+		}
+	}
+	_ = p.Signal(syscall.SIGKILL)
+}
+
+// Signal sends the signal to the process group
+func (p *localProcess) Signal(sig syscall.Signal) error {
+	return syscall.Kill(-p.cmd.Process.Pid, sig)
+}
@@ -0,0 +1,141 @@
+// Package executor runs the external commands required by the cli
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_String(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("npm install --save-dev mochawesome", Command{Name: "npm", Args: []string{"install", "--save-dev", "mochawesome"}}.String())
+}
+
+func TestLocal(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+
+	output, err := Output(context.Background(), Local{}, Command{
+		Name: "sh",
+		Args: []string{"-c", "pwd && echo $EXECUTOR_TEST"},
+		Dir:  dir,
+		Env:  []string{"EXECUTOR_TEST=ok"},
+	})
+	assert.NoError(err)
+	assert.Equal(fmt.Sprintf("%s\nok\n", dir), string(output))
+
+	_, err = Output(context.Background(), Local{}, Command{Name: "sh", Args: []string{"-c", "echo failed >&2; exit 3"}})
+	assert.Error(err)
+	e, ok := err.(*exec.ExitError)
+	assert.True(ok)
+	assert.Equal(3, e.ExitCode())
+	assert.Equal("failed\n", string(e.Stderr))
+
+	_, err = Output(context.Background(), Local{}, Command{Name: "command-that-does-not-exist"})
+	assert.Error(err)
+}
+
+func TestLocal_timeout(t *testing.T) {
+	assert := assert.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	// the child of sh must be killed with its parent
+	_, err := Output(ctx, Local{}, Command{Name: "sh", Args: []string{"-c", "sleep 10 & wait"}})
+	assert.Error(err)
+	assert.Less(time.Since(start), 5*time.Second)
+}
+
+func TestLocal_gracePeriod(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		{
+			script:   "trap 'echo terminated; exit 1' TERM; sleep 10 & wait",
+			expected: "terminated\n",
+		},
+		{
+			script: "trap '' TERM; sleep 10 & wait; sleep 10",
+		},
+	}
+
+	for _, tc := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		start := time.Now()
+		output, err := Output(ctx, Local{GracePeriod: 500 * time.Millisecond}, Command{Name: "sh", Args: []string{"-c", tc.script}})
+		cancel()
+		assert.Error(err)
+		assert.Equal(tc.expected, string(output))
+		assert.Less(time.Since(start), 5*time.Second)
+	}
+}
+
+func TestLocal_signal(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := Local{}.Start(context.Background(), Command{Name: "sleep", Args: []string{"10"}})
+	assert.NoError(err)
+	assert.NoError(p.Signal(syscall.SIGTERM))
+	_, err = p.Wait()
+	assert.Error(err)
+}
+
+func TestRecorder(t *testing.T) {
+	assert := assert.New(t)
+	r := &Recorder{
+		Handler: func(ctx context.Context, cmd Command) ([]byte, error) {
+			switch cmd.Name {
+			case "cypress":
+				return []byte("Cypress package version: 10.10.0\n"), nil
+			case "sleep":
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("%s failed", cmd)
+		},
+	}
+
+	output, err := Output(context.Background(), r, Command{Name: "cypress", Args: []string{"--version"}})
+	assert.NoError(err)
+	assert.True(strings.HasPrefix(string(output), "Cypress package version"))
+
+	_, err = Output(context.Background(), r, Command{Name: "npm", Args: []string{"install"}})
+	assert.EqualError(err, "npm install failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = Output(ctx, r, Command{Name: "sleep"})
+	assert.ErrorIs(err, context.DeadlineExceeded)
+
+	p, err := r.Start(context.Background(), Command{Name: "sleep"})
+	assert.NoError(err)
+	assert.NoError(p.Signal(syscall.SIGTERM))
+	_, err = p.Wait()
+	assert.ErrorIs(err, context.Canceled)
+
+	assert.Equal(
+		[]string{"cypress --version", "npm install", "sleep", "sleep"},
+		commandLines(r.Commands()),
+	)
+
+	_, err = Output(context.Background(), &Recorder{}, Command{Name: "npm"})
+	assert.NoError(err)
+}
+
+func commandLines(commands []Command) (z []string) {
+	for _, cmd := range commands {
+		z = append(z, cmd.String())
+	}
+	return
+}
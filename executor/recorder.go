@@ -0,0 +1,71 @@
+// Package executor runs the external commands required by the cli
+package executor
+
+import (
+	"context"
+	"sync"
+	"syscall"
+)
+
+// Recorder is an Executor recording commands instead of running them.
+// It allows to check the commands run by the cli and to simulate their
+// output, failures and timeouts without any program installed
+type Recorder struct {
+	// Handler simulates the execution of the command and returns its standard output.
+	// The context is done when the process is signaled or the context given to Start is done.
+	// Commands succeed with an empty output when Handler is nil
+	Handler func(ctx context.Context, cmd Command) ([]byte, error)
+
+	mu       sync.Mutex
+	commands []Command
+}
+
+// recordedProcess is a process started by Recorder
+type recordedProcess struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	output []byte
+	err    error
+}
+
+// Start records the command and runs Handler in the background
+func (r *Recorder) Start(ctx context.Context, cmd Command) (Process, error) {
+	r.mu.Lock()
+	r.commands = append(r.commands, cmd)
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	p := &recordedProcess{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(p.done)
+		defer cancel()
+		if r.Handler != nil {
+			p.output, p.err = r.Handler(ctx, cmd)
+		}
+	}()
+	return p, nil
+}
+
+// Commands returns the recorded commands in the order they have been started
+func (r *Recorder) Commands() []Command {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	z := make([]Command, len(r.commands))
+	copy(z, r.commands)
+	return z
+}
+
+// Wait waits for Handler to return
+func (p *recordedProcess) Wait() ([]byte, error) {
+	<-p.done
+	return p.output, p.err
+}
+
+// Signal cancels the context given to Handler
+func (p *recordedProcess) Signal(sig syscall.Signal) error {
+	p.cancel()
+	return nil
+}
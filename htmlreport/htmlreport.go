@@ -0,0 +1,76 @@
+// Package htmlreport renders the results of an execution as a self-contained static html page
+package htmlreport
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Lord-Y/cypress-parallel-cli/mochawesome"
+)
+
+//go:embed templates/*.html
+var templates embed.FS
+
+var page = template.Must(template.New("report.html").Funcs(template.FuncMap{
+	"duration": duration,
+	"lower":    strings.ToLower,
+}).ParseFS(templates, "templates/*.html"))
+
+// Page is the content of the report
+type Page struct {
+	Title     string            // Title of the page
+	Generated time.Time         // When the page has been generated
+	Stats     mochawesome.Stats // Stats of the merged mochawesome reports
+	Specs     []Spec            // Result of each spec
+}
+
+// Spec is the result of a spec in a browser
+type Spec struct {
+	Name     string              // Path of the spec
+	Browser  string              // Browser of the spec, empty when not set
+	Status   string              // Execution status like DONE or FAILED
+	Duration int64               // Wall-clock duration in milliseconds
+	Error    string              // Error preventing the spec to run or to complete
+	Suites   []mochawesome.Suite // Root suites of the mochawesome report of the spec
+}
+
+// Count returns the number of specs with the given status
+func (p Page) Count(status string) (z int) {
+	for _, s := range p.Specs {
+		if s.Status == status {
+			z++
+		}
+	}
+	return
+}
+
+// Render writes the html page in w
+func Render(w io.Writer, p Page) error {
+	return page.Execute(w, p)
+}
+
+// Write writes the html page in file
+func Write(file string, p Page) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err = Render(f, p); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// duration formats milliseconds like 1.5s
+func duration(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
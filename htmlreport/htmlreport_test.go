@@ -0,0 +1,72 @@
+// Package htmlreport renders the results of an execution as a self-contained static html page
+package htmlreport
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Lord-Y/cypress-parallel-cli/mochawesome"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	assert := assert.New(t)
+	p := Page{
+		Title:     "uid",
+		Generated: time.Date(2022, 10, 14, 12, 0, 0, 0, time.UTC),
+		Stats:     mochawesome.Stats{Tests: 2, Passes: 1, Failures: 1, Duration: 1500},
+		Specs: []Spec{
+			{
+				Name:     "cypress/e2e/login.cy.js",
+				Browser:  "chrome",
+				Status:   "FAILED",
+				Duration: 2500,
+				Suites: []mochawesome.Suite{{
+					File: "cypress/e2e/login.cy.js",
+					Suites: []mochawesome.Suite{{
+						Title: "login",
+						Tests: []mochawesome.Test{
+							{Title: "logs in", Duration: 1200, Pass: true},
+							{Title: "shows <b>errors</b>", Duration: 300, Fail: true, Code: "cy.get('.error')", Err: mochawesome.Error{Message: "AssertionError: expected .error", Stack: "at login.cy.js:3:9"}},
+						},
+					}},
+				}},
+			},
+			{
+				Name:     "cypress/e2e/users.cy.js",
+				Status:   "TIMEOUT",
+				Duration: 300000,
+				Error:    "spec timeout reached after 5m0s",
+			},
+		},
+	}
+
+	out := new(bytes.Buffer)
+	assert.NoError(Render(out, p))
+	z := out.String()
+	assert.Contains(z, "<title>uid</title>")
+	assert.Contains(z, "Generated on 2022-10-14 12:00:00 UTC")
+	assert.Contains(z, "<tr><td>2</td><td>0</td><td>0</td><td>1</td><td>1</td><td>0</td><td>0</td></tr>")
+	assert.Contains(z, `<details class="spec failed" open>`)
+	assert.Contains(z, "cypress/e2e/login.cy.js in chrome <span class=\"duration\">2.5s</span>")
+	assert.Contains(z, "<summary><span class=\"state\">passed</span> logs in <span class=\"duration\">1.2s</span></summary>")
+	assert.Contains(z, `<details class="test failed" open>`)
+	assert.Contains(z, "shows &lt;b&gt;errors&lt;/b&gt;")
+	assert.Contains(z, `<pre class="error">AssertionError: expected .error</pre>`)
+	assert.Contains(z, `<pre class="stack">at login.cy.js:3:9</pre>`)
+	assert.Contains(z, `<pre class="code">cy.get(&#39;.error&#39;)</pre>`)
+	assert.Contains(z, `<pre class="error">spec timeout reached after 5m0s</pre>`)
+	// the page is self-contained
+	assert.NotContains(z, "<script")
+	assert.NotContains(z, "<link")
+	assert.NotContains(z, "http")
+
+	file := filepath.Join(t.TempDir(), "reports", "index.html")
+	assert.NoError(Write(file, p))
+	content, err := os.ReadFile(file)
+	assert.NoError(err)
+	assert.Equal(z, string(content))
+}
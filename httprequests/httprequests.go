@@ -19,7 +19,13 @@ func init() {
 
 // retryQuery permit to perform retries when HTTP requests fails
 func retryQuery() (z *http.Client) {
-	retryClient := retryablehttp.NewClient()
+	return RetryClient().StandardClient()
+}
+
+// RetryClient returns the client retrying requests on connection errors and 5xx status codes.
+// Retries are configured with HTTP_RETRY_MAX, HTTP_RETRY_WAIT_MIN and HTTP_RETRY_WAIT_MAX, failed attempts are logged with their method and url only
+func RetryClient() (retryClient *retryablehttp.Client) {
+	retryClient = retryablehttp.NewClient()
 
 	if strings.TrimSpace(os.Getenv("HTTP_RETRY_MAX")) != "" {
 		max, err := strconv.Atoi(os.Getenv("HTTP_RETRY_MAX"))
@@ -64,7 +70,7 @@ func retryQuery() (z *http.Client) {
 			}
 		}
 	}
-	return retryClient.StandardClient()
+	return
 }
 
 // PerformRequests permit to perform HTTP requests
@@ -0,0 +1,119 @@
+// Package junit writes test results in the JUnit XML format read by CI servers
+package junit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Testsuites is the root element of the report
+type Testsuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Name     string      `xml:"name,attr,omitempty"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Suites   []Testsuite `xml:"testsuite"`
+}
+
+// Testsuite holds the testcases of a spec
+type Testsuite struct {
+	Name       string      `xml:"name,attr"`
+	Tests      int         `xml:"tests,attr"`
+	Failures   int         `xml:"failures,attr"`
+	Errors     int         `xml:"errors,attr"`
+	Skipped    int         `xml:"skipped,attr"`
+	Time       string      `xml:"time,attr"`
+	Properties *Properties `xml:"properties,omitempty"`
+	Testcases  []Testcase  `xml:"testcase"`
+
+	duration time.Duration // duration formatted in Time
+}
+
+// Properties describe a testsuite
+type Properties struct {
+	Properties []Property `xml:"property"`
+}
+
+// Property is a name value pair describing a testsuite
+type Property struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// Testcase is a single test, at most one of Failure, Error and Skipped is set
+type Testcase struct {
+	Name      string  `xml:"name,attr"`
+	Classname string  `xml:"classname,attr"`
+	Time      string  `xml:"time,attr"`
+	Failure   *Result `xml:"failure,omitempty"`
+	Error     *Result `xml:"error,omitempty"`
+	Skipped   *Result `xml:"skipped,omitempty"`
+}
+
+// Result is the failure, error or skip reason of a testcase
+type Result struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// NewTestsuite returns an empty testsuite which took duration
+func NewTestsuite(name string, duration time.Duration) Testsuite {
+	return Testsuite{Name: name, Time: Seconds(duration), duration: duration}
+}
+
+// AddProperty adds a property to the testsuite
+func (s *Testsuite) AddProperty(name string, value string) {
+	if s.Properties == nil {
+		s.Properties = &Properties{}
+	}
+	s.Properties.Properties = append(s.Properties.Properties, Property{Name: name, Value: value})
+}
+
+// Add adds the testcase and updates the counters of the testsuite
+func (s *Testsuite) Add(tc Testcase) {
+	s.Tests++
+	switch {
+	case tc.Failure != nil:
+		s.Failures++
+	case tc.Error != nil:
+		s.Errors++
+	case tc.Skipped != nil:
+		s.Skipped++
+	}
+	s.Testcases = append(s.Testcases, tc)
+}
+
+// Seconds formats the duration in seconds with a millisecond precision
+func Seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// Write writes the testsuites in file with the totals of all testsuites
+func Write(file string, name string, suites []Testsuite) error {
+	z := Testsuites{Name: name, Suites: suites}
+	var duration time.Duration
+	for _, s := range suites {
+		z.Tests += s.Tests
+		z.Failures += s.Failures
+		z.Errors += s.Errors
+		z.Skipped += s.Skipped
+		duration += s.duration
+	}
+	z.Time = Seconds(duration)
+
+	content, err := xml.MarshalIndent(z, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, append([]byte(xml.Header), append(content, '\n')...), 0644)
+}
@@ -0,0 +1,48 @@
+// Package junit writes test results in the JUnit XML format read by CI servers
+package junit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	assert := assert.New(t)
+	login := NewTestsuite("cypress/e2e/login.cy.js", 1500*time.Millisecond)
+	login.AddProperty("browser", "chrome")
+	login.Add(Testcase{Name: "login logs in", Classname: "cypress/e2e/login.cy.js", Time: "1.200"})
+	login.Add(Testcase{Name: "login fails", Classname: "cypress/e2e/login.cy.js", Time: "0.300", Failure: &Result{Message: "AssertionError: expected <b> to be visible", Text: "AssertionError\n    at login.cy.js:12:20"}})
+	login.Add(Testcase{Name: "login remembers me", Classname: "cypress/e2e/login.cy.js", Time: "0.000", Skipped: &Result{Message: "pending"}})
+	users := NewTestsuite("cypress/e2e/users.cy.js", 5*time.Minute)
+	users.Add(Testcase{Name: "cypress/e2e/users.cy.js", Classname: "cypress/e2e/users.cy.js", Time: "300.000", Error: &Result{Message: "spec timeout reached after 5m0s", Type: "TIMEOUT"}})
+
+	file := filepath.Join(t.TempDir(), "reports", "junit.xml")
+	assert.NoError(Write(file, "uid", []Testsuite{login, users}))
+	content, err := os.ReadFile(file)
+	assert.NoError(err)
+	assert.Equal(`<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="uid" tests="4" failures="1" errors="1" skipped="1" time="301.500">
+  <testsuite name="cypress/e2e/login.cy.js" tests="3" failures="1" errors="0" skipped="1" time="1.500">
+    <properties>
+      <property name="browser" value="chrome"></property>
+    </properties>
+    <testcase name="login logs in" classname="cypress/e2e/login.cy.js" time="1.200"></testcase>
+    <testcase name="login fails" classname="cypress/e2e/login.cy.js" time="0.300">
+      <failure message="AssertionError: expected &lt;b&gt; to be visible">AssertionError&#xA;    at login.cy.js:12:20</failure>
+    </testcase>
+    <testcase name="login remembers me" classname="cypress/e2e/login.cy.js" time="0.000">
+      <skipped message="pending"></skipped>
+    </testcase>
+  </testsuite>
+  <testsuite name="cypress/e2e/users.cy.js" tests="1" failures="0" errors="1" skipped="0" time="300.000">
+    <testcase name="cypress/e2e/users.cy.js" classname="cypress/e2e/users.cy.js" time="300.000">
+      <error message="spec timeout reached after 5m0s" type="TIMEOUT"></error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, string(content))
+}
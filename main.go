@@ -13,6 +13,7 @@ import (
 // make vars public for unit testing
 var (
 	cypress        *cli.Command
+	playwright     *cli.Command
 	versionDetails *cli.Command
 )
 
@@ -20,6 +21,7 @@ func init() {
 	logger.SetLoggerLogLevel()
 
 	cypress = cmd.Cypress(&cli.Context{})
+	playwright = cmd.Playwright(&cli.Context{})
 	versionDetails = cmd.VersionDetails(&cli.Context{})
 }
 
@@ -32,11 +34,13 @@ func main() {
 	app.EnableBashCompletion = true
 	app.Commands = []*cli.Command{
 		cypress,
+		playwright,
 		versionDetails,
 	}
 
 	err := app.Run(os.Args)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Error occured while executing the program")
+		log.Error().Err(err).Msg("Error occured while executing the program")
+		os.Exit(cmd.ExitCode(err))
 	}
 }
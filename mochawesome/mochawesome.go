@@ -0,0 +1,125 @@
+// Package mochawesome parses the json reports written by the mochawesome reporter
+package mochawesome
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Test outcomes
+const (
+	Passed  = "passed"  // test passed
+	Failed  = "failed"  // test or one of its hooks failed
+	Pending = "pending" // test marked as pending with it.skip or without body
+	Skipped = "skipped" // test not run because a hook failed
+)
+
+// Report is a mochawesome json report
+type Report struct {
+	Stats   Stats   `json:"stats"`   // Aggregated stats of the run
+	Results []Suite `json:"results"` // Root suite of each spec file
+}
+
+// Stats are the aggregated stats of a report
+type Stats struct {
+	Suites   int    `json:"suites"`   // Number of suites
+	Tests    int    `json:"tests"`    // Number of tests
+	Passes   int    `json:"passes"`   // Number of passed tests
+	Pending  int    `json:"pending"`  // Number of pending tests
+	Failures int    `json:"failures"` // Number of failed tests and hooks
+	Skipped  int    `json:"skipped"`  // Number of tests skipped after a hook failure
+	Start    string `json:"start"`    // Start of the run in RFC 3339 format
+	End      string `json:"end"`      // End of the run in RFC 3339 format
+	Duration int64  `json:"duration"` // Duration of the run in milliseconds
+}
+
+// Suite is a describe block, root suites hold the tests declared outside of any block
+type Suite struct {
+	Title    string  `json:"title"`    // Title of the suite, empty for root suites
+	File     string  `json:"file"`     // Spec file of root suites
+	Tests    []Test  `json:"tests"`    // Tests of the suite
+	Suites   []Suite `json:"suites"`   // Nested suites
+	Duration int64   `json:"duration"` // Duration of the suite in milliseconds
+}
+
+// Test is a single it block
+type Test struct {
+	Title     string `json:"title"`     // Title of the test
+	FullTitle string `json:"fullTitle"` // Title of the test prefixed by the titles of its suites
+	Duration  int64  `json:"duration"`  // Duration of the test in milliseconds
+	State     string `json:"state"`     // passed, failed or pending, empty when skipped
+	Pass      bool   `json:"pass"`      // Whether the test passed
+	Fail      bool   `json:"fail"`      // Whether the test failed
+	Pending   bool   `json:"pending"`   // Whether the test is pending
+	Skipped   bool   `json:"skipped"`   // Whether the test has been skipped
+	Code      string `json:"code"`      // Code snippet of the test
+	Err       Error  `json:"err"`       // Error of failed tests
+}
+
+// Error is the error of a failed test
+type Error struct {
+	Message string `json:"message"` // Error message
+	Stack   string `json:"estack"`  // Stack trace
+	Diff    string `json:"diff"`    // Diff between actual and expected values of assertions
+}
+
+// Parse returns the report of the json content
+func Parse(content []byte) (z Report, err error) {
+	if err = json.Unmarshal(content, &z); err != nil {
+		return z, fmt.Errorf("invalid mochawesome report: %w", err)
+	}
+	return
+}
+
+// Tests returns the tests of every suite of the report in declaration order
+func (r Report) Tests() (z []Test) {
+	for _, s := range r.Results {
+		z = append(z, s.AllTests()...)
+	}
+	return
+}
+
+// AllTests returns the tests of the suite and of its nested suites in declaration order
+func (s Suite) AllTests() (z []Test) {
+	z = append(z, s.Tests...)
+	for _, nested := range s.Suites {
+		z = append(z, nested.AllTests()...)
+	}
+	return
+}
+
+// Outcome returns whether the test passed, failed, is pending or has been skipped
+func (t Test) Outcome() string {
+	switch {
+	case t.Fail:
+		return Failed
+	case t.Pass:
+		return Passed
+	case t.Pending:
+		return Pending
+	default:
+		return Skipped
+	}
+}
+
+// Merge returns a single report with the results of every report and their stats summed,
+// like mochawesome-merge does. Start and end are the earliest start and the latest end
+func Merge(reports ...Report) (z Report) {
+	for _, r := range reports {
+		z.Results = append(z.Results, r.Results...)
+		z.Stats.Suites += r.Stats.Suites
+		z.Stats.Tests += r.Stats.Tests
+		z.Stats.Passes += r.Stats.Passes
+		z.Stats.Pending += r.Stats.Pending
+		z.Stats.Failures += r.Stats.Failures
+		z.Stats.Skipped += r.Stats.Skipped
+		z.Stats.Duration += r.Stats.Duration
+		if r.Stats.Start != "" && (z.Stats.Start == "" || r.Stats.Start < z.Stats.Start) {
+			z.Stats.Start = r.Stats.Start
+		}
+		if r.Stats.End > z.Stats.End {
+			z.Stats.End = r.Stats.End
+		}
+	}
+	return
+}
@@ -0,0 +1,163 @@
+// Package mochawesome parses the json reports written by the mochawesome reporter
+package mochawesome
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const report = `{
+  "stats": {
+    "suites": 2,
+    "tests": 4,
+    "passes": 1,
+    "pending": 1,
+    "failures": 1,
+    "start": "2022-10-14T12:00:00.000Z",
+    "end": "2022-10-14T12:00:03.000Z",
+    "duration": 3000,
+    "testsRegistered": 4,
+    "passPercent": 33.33,
+    "pendingPercent": 25,
+    "other": 0,
+    "hasOther": false,
+    "skipped": 1,
+    "hasSkipped": true
+  },
+  "results": [
+    {
+      "uuid": "1",
+      "title": "",
+      "fullFile": "cypress/e2e/login.cy.js",
+      "file": "cypress/e2e/login.cy.js",
+      "beforeHooks": [],
+      "afterHooks": [],
+      "tests": [],
+      "suites": [
+        {
+          "uuid": "2",
+          "title": "login",
+          "tests": [
+            {
+              "title": "logs in",
+              "fullTitle": "login logs in",
+              "timedOut": null,
+              "duration": 1200,
+              "state": "passed",
+              "speed": "slow",
+              "pass": true,
+              "fail": false,
+              "pending": false,
+              "context": null,
+              "code": "cy.login('admin')",
+              "err": {},
+              "uuid": "3",
+              "parentUUID": "2",
+              "isHook": false,
+              "skipped": false
+            },
+            {
+              "title": "remembers me",
+              "fullTitle": "login remembers me",
+              "duration": 0,
+              "state": "pending",
+              "pass": false,
+              "fail": false,
+              "pending": true,
+              "code": "",
+              "err": {},
+              "skipped": false
+            }
+          ],
+          "suites": [
+            {
+              "uuid": "4",
+              "title": "with a wrong password",
+              "tests": [
+                {
+                  "title": "shows an error",
+                  "fullTitle": "login with a wrong password shows an error",
+                  "duration": 1500,
+                  "state": "failed",
+                  "pass": false,
+                  "fail": true,
+                  "pending": false,
+                  "code": "cy.get('.error').should('be.visible')",
+                  "err": {
+                    "message": "AssertionError: Timed out retrying after 4000ms: Expected to find element: .error, but never found it.",
+                    "estack": "AssertionError: Timed out retrying after 4000ms\n    at Context.eval (webpack:///./cypress/e2e/login.cy.js:12:20)",
+                    "diff": null
+                  },
+                  "skipped": false
+                },
+                {
+                  "title": "locks the account",
+                  "fullTitle": "login with a wrong password locks the account",
+                  "duration": 0,
+                  "state": null,
+                  "pass": false,
+                  "fail": false,
+                  "pending": false,
+                  "code": "cy.get('.locked')",
+                  "err": {},
+                  "skipped": true
+                }
+              ],
+              "suites": [],
+              "duration": 1500
+            }
+          ],
+          "duration": 2700
+        }
+      ]
+    }
+  ],
+  "meta": {
+    "mocha": {"version": "7.0.1"},
+    "mochawesome": {"options": {"quiet": false}, "version": "7.1.3"}
+  }
+}`
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+	z, err := Parse([]byte(report))
+	assert.NoError(err)
+	assert.Equal(Stats{Suites: 2, Tests: 4, Passes: 1, Pending: 1, Failures: 1, Skipped: 1, Start: "2022-10-14T12:00:00.000Z", End: "2022-10-14T12:00:03.000Z", Duration: 3000}, z.Stats)
+	assert.Equal("cypress/e2e/login.cy.js", z.Results[0].File)
+	assert.Equal("login", z.Results[0].Suites[0].Title)
+
+	tests := z.Tests()
+	assert.Len(tests, 4)
+	var titles, outcomes []string
+	for _, test := range tests {
+		titles = append(titles, test.FullTitle)
+		outcomes = append(outcomes, test.Outcome())
+	}
+	assert.Equal([]string{"login logs in", "login remembers me", "login with a wrong password shows an error", "login with a wrong password locks the account"}, titles)
+	assert.Equal([]string{Passed, Pending, Failed, Skipped}, outcomes)
+	assert.Equal(int64(1500), tests[2].Duration)
+	assert.Equal("cy.get('.error').should('be.visible')", tests[2].Code)
+	assert.Contains(tests[2].Err.Message, "Expected to find element: .error")
+	assert.Contains(tests[2].Err.Stack, "login.cy.js:12:20")
+
+	_, err = Parse([]byte(`{"stats": []}`))
+	assert.Error(err)
+}
+
+func TestMerge(t *testing.T) {
+	assert := assert.New(t)
+	login := Report{
+		Stats:   Stats{Suites: 1, Tests: 2, Passes: 1, Failures: 1, Start: "2022-10-14T12:00:01.000Z", End: "2022-10-14T12:00:03.000Z", Duration: 2000},
+		Results: []Suite{{File: "cypress/e2e/login.cy.js"}},
+	}
+	users := Report{
+		Stats:   Stats{Suites: 2, Tests: 3, Passes: 2, Pending: 1, Start: "2022-10-14T12:00:00.000Z", End: "2022-10-14T12:00:02.000Z", Duration: 2000},
+		Results: []Suite{{File: "cypress/e2e/users.cy.js"}},
+	}
+
+	z := Merge(login, users, Report{})
+	assert.Equal(Stats{Suites: 3, Tests: 5, Passes: 3, Pending: 1, Failures: 1, Start: "2022-10-14T12:00:00.000Z", End: "2022-10-14T12:00:03.000Z", Duration: 4000}, z.Stats)
+	assert.Equal([]Suite{{File: "cypress/e2e/login.cy.js"}, {File: "cypress/e2e/users.cy.js"}}, z.Results)
+	assert.Equal(Report{}, Merge())
+}
@@ -0,0 +1,327 @@
+// Package runner describes the e2e frameworks able to run specs
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Lord-Y/cypress-parallel-cli/executor"
+	"github.com/Lord-Y/cypress-parallel-cli/mochawesome"
+	"github.com/Lord-Y/cypress-parallel-cli/xvfb"
+	"github.com/Lord-Y/golang-tools/tools"
+	"github.com/hashicorp/go-version"
+	"github.com/rs/zerolog/log"
+)
+
+// reporterDir is the directory of the project in which mochawesome is installed,
+// apart from the project dependencies so their lockfile is left untouched
+const reporterDir = ".cypress-parallel-cli"
+
+// Cypress runs specs with the cypress binary installed in the image and reports them with mochawesome
+type Cypress struct{}
+
+// Name returns the name of the framework
+func (Cypress) Name() string {
+	return "cypress"
+}
+
+// Install installs the project dependencies with its package manager, or uninstalls the cypress package
+// of the project when its version conflicts with the one of the image which also installs the others,
+// then installs mochawesome with npm. Cypress plugins of the project are kept and the binary
+// of the cypress package is not downloaded when the image has one
+func (Cypress) Install(ctx context.Context, e executor.Executor, dir string) error {
+	var zp map[string]interface{}
+	packages, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		log.Error().Err(err).Msg("Error occured while getting package.json file")
+		return err
+	}
+
+	err = json.Unmarshal(packages, &zp)
+	if err != nil {
+		log.Error().Err(err).Msgf("Error occured while unmarshalling package.json")
+		return err
+	}
+
+	pm, err := DetectPackageManager(dir)
+	if err != nil {
+		log.Error().Err(err).Msg("Error occured while detecting package manager")
+		return err
+	}
+	log.Debug().Msgf("Package manager %s, lockfile %s", pm.Name, pm.Lockfile)
+
+	var required string
+	for _, dependencies := range []string{"dependencies", "devDependencies"} {
+		if zp[dependencies] == nil {
+			continue
+		}
+		m, err := tools.ConvertMapStringInterfaceToMapStringString(zp[dependencies].(map[string]interface{}))
+		if err != nil {
+			log.Error().Err(err).Msgf("Error occured while converting %s", dependencies)
+			return err
+		}
+		if v, ok := m["cypress"]; ok {
+			required = v
+		}
+	}
+
+	install := pm.Install(dir)
+	if required != "" && cypressConflicts(ctx, e, dir, lockedCypress(dir, pm, required)) {
+		install = pm.Remove(dir, "cypress")
+	}
+	// specs run with the cypress of the image, the binary of the project package would never be used
+	if _, err := lookPath("cypress"); err == nil {
+		install.Env = append(install.Env, "CYPRESS_INSTALL_BINARY=0")
+	}
+	output, err := executor.Output(ctx, e, install)
+	log.Debug().Msgf("User packages install output %s", string(output))
+	if err != nil {
+		log.Error().Err(err).Msgf("Error occured while installing user packages")
+		return err
+	}
+
+	output, err = executor.Output(ctx, e, executor.Command{Name: "npm", Args: []string{"install", "--prefix", reporterDir, "--no-save", "--no-package-lock", "mochawesome"}, Dir: dir})
+	log.Debug().Msgf("Mochawesome install output %s", string(output))
+	if err != nil {
+		log.Error().Err(err).Msgf("Error occured while installing mochawesome")
+		return err
+	}
+	return nil
+}
+
+// lockedCypress returns the version of the cypress package resolved in the npm lockfile of the project.
+// The required range is returned when it is not found, like with yarn and pnpm lockfiles
+func lockedCypress(dir string, pm PackageManager, required string) string {
+	if pm.Name != NPM || pm.Lockfile == "" {
+		return required
+	}
+	content, err := os.ReadFile(filepath.Join(dir, pm.Lockfile))
+	if err != nil {
+		log.Warn().Err(err).Msgf("Error occured while reading %s", pm.Lockfile)
+		return required
+	}
+	type locked struct {
+		Version string `json:"version"`
+	}
+	// packages is written by npm 7 and later, dependencies by older versions
+	var lockfile struct {
+		Packages     map[string]locked `json:"packages"`
+		Dependencies map[string]locked `json:"dependencies"`
+	}
+	if err = json.Unmarshal(content, &lockfile); err != nil {
+		log.Warn().Err(err).Msgf("Error occured while unmarshalling %s", pm.Lockfile)
+		return required
+	}
+	if p := lockfile.Packages["node_modules/cypress"]; p.Version != "" {
+		return p.Version
+	}
+	if p := lockfile.Dependencies["cypress"]; p.Version != "" {
+		return p.Version
+	}
+	return required
+}
+
+// cypressConflicts returns whether the cypress of the image in PATH does not satisfy the npm range
+// required by the project, in which case the cypress package of the project must be uninstalled.
+// Ranges which cannot be checked, like tags or urls, conflict
+func cypressConflicts(ctx context.Context, e executor.Executor, dir string, required string) bool {
+	image := PathCypressVersion()
+	if image == "" {
+		if _, err := lookPath("cypress"); err != nil {
+			log.Info().Msgf("Cypress not found in PATH, cypress %s of the project is kept", required)
+			return false
+		}
+		output, err := executor.Output(ctx, e, executor.Command{Name: "cypress", Args: []string{"--version"}, Dir: dir, Probe: true})
+		if err != nil {
+			log.Warn().Err(err).Msgf("Error occured while running cypress version command, cypress %s of the project is uninstalled", required)
+			return true
+		}
+		image = parseCypressVersion(string(output)).Package
+	}
+
+	ok, err := satisfies(required, image)
+	switch {
+	case err != nil:
+		log.Info().Err(err).Msgf("Cypress %s required by the project cannot be compared to cypress %s of the image, it is uninstalled", required, image)
+		return true
+	case ok:
+		log.Info().Msgf("Cypress %s of the image satisfies cypress %s required by the project, it is kept", image, required)
+		return false
+	}
+	log.Info().Msgf("Cypress %s of the image conflicts with cypress %s required by the project, it is uninstalled", image, required)
+	return true
+}
+
+// Dependencies returns node_modules and the directory of mochawesome
+func (Cypress) Dependencies() []string {
+	return []string{"node_modules", reporterDir}
+}
+
+// SpecNames returns the default names of cypress spec files
+func (Cypress) SpecNames() []string {
+	return []string{"*.cy.*"}
+}
+
+// Version returns the versions of cypress in PATH
+func (Cypress) Version(ctx context.Context, e executor.Executor, dir string) (Version, error) {
+	return DetectCypress(ctx, e, dir)
+}
+
+// Browsers returns the browsers detected by cypress info named like chrome and chrome:beta.
+// Electron is bundled with cypress so it is always installed
+func (Cypress) Browsers(ctx context.Context, e executor.Executor, dir string) ([]string, error) {
+	output, err := executor.Output(ctx, e, executor.Command{Name: "cypress", Args: []string{"info"}, Dir: dir, Env: []string{"NO_COLOR=1"}, Probe: true})
+	if err != nil {
+		log.Error().Err(err).Msgf("Error occured while running cypress info command %s", string(output))
+		return nil, err
+	}
+	return parseCypressInfo(string(output)), nil
+}
+
+// parseCypressInfo returns the browsers listed by cypress info with their name
+// and channel lines like "- Name: chrome" and "- Channel: stable"
+func parseCypressInfo(output string) []string {
+	z := []string{"electron"}
+	var name string
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "- Name":
+			name = value
+			if name != "electron" {
+				z = append(z, name)
+			}
+		case "- Channel":
+			if name != "" {
+				z = append(z, fmt.Sprintf("%s:%s", name, value))
+			}
+		}
+	}
+	return z
+}
+
+// Display returns true when Xvfb is required by the browser
+func (Cypress) Display(x Execution) bool {
+	return xvfb.Required(x.Browser, x.Headed)
+}
+
+// Command returns the cypress run command writing a mochawesome report.
+// Browsers run headless by default since cypress 10.0.0, --headless is added for older versions.
+// Screenshots and videos folders are overridden when Artifacts is set
+func (Cypress) Command(x Execution) (z executor.Command, err error) {
+	v1, err := version.NewVersion(x.Version)
+	if err != nil {
+		log.Error().Err(err).Msgf("Error occured while initializing cypress version v1")
+		return
+	}
+	v2, err := version.NewVersion("10.0.0")
+	if err != nil {
+		log.Error().Err(err).Msgf("Error occured while initializing cypress version v2")
+		return
+	}
+
+	args := []string{"run"}
+	// cypress uses electron when no browser is given
+	if x.Browser != "" {
+		args = append(args, "--browser", x.Browser)
+	}
+	if x.Headed {
+		args = append(args, "--headed")
+	} else if v1.LessThan(v2) {
+		args = append(args, "--headless")
+	}
+	if x.Component {
+		args = append(args, "--component")
+	}
+	if x.Config != "" {
+		args = append(args, "--config-file", x.Config)
+	}
+	overrides := x.Overrides
+	if x.Artifacts != "" {
+		overrides = make(map[string]string, len(x.Overrides)+2)
+		for k, v := range x.Overrides {
+			overrides[k] = v
+		}
+		overrides["screenshotsFolder"] = filepath.Join(x.Artifacts, "screenshots")
+		overrides["videosFolder"] = filepath.Join(x.Artifacts, "videos")
+	}
+	if len(overrides) > 0 {
+		args = append(args, "--config", joinValues(overrides))
+	}
+	if len(x.Env) > 0 {
+		args = append(args, "--env", joinValues(x.Env))
+	}
+	if x.Quiet {
+		args = append(args, "--quiet")
+	}
+	reporterOptions := fmt.Sprintf("reportFilename=%s", x.Report)
+	if x.ReportDir != "" {
+		reporterOptions += fmt.Sprintf(",reportDir=%s", x.ReportDir)
+	}
+	args = append(
+		args,
+		"--spec",
+		x.Spec,
+		"--reporter",
+		"./"+reporterDir+"/node_modules/mochawesome",
+		"--reporter-options",
+		reporterOptions,
+	)
+
+	return executor.Command{
+		Name: "cypress",
+		Args: args,
+		Dir:  x.Dir,
+		Env: []string{
+			fmt.Sprintf("NO_COLOR=%d", 1),
+		},
+	}, nil
+}
+
+// ResultFile returns the mochawesome json report of the execution
+func (Cypress) ResultFile(x Execution) string {
+	if x.ReportDir != "" {
+		return filepath.Join(x.ReportDir, x.Report+".json")
+	}
+	return filepath.Join(x.Dir, "mochawesome-report", x.Report+".json")
+}
+
+// ParseResult returns the compacted mochawesome report with its stats and the outcome of each test
+func (Cypress) ParseResult(content []byte) (z Result, err error) {
+	report, err := mochawesome.Parse(content)
+	if err != nil {
+		return
+	}
+	if z.Content, err = compact(content); err != nil {
+		return
+	}
+	z.Stats = &Stats{
+		Tests:    report.Stats.Tests,
+		Passes:   report.Stats.Passes,
+		Failures: report.Stats.Failures,
+		Pending:  report.Stats.Pending,
+		Skipped:  report.Stats.Skipped,
+	}
+	for _, t := range report.Tests() {
+		test := Test{
+			Title:    t.FullTitle,
+			State:    t.Outcome(),
+			Duration: t.Duration,
+		}
+		if t.Fail {
+			test.Error = t.Err.Message
+			test.Code = t.Code
+			test.Stack = t.Err.Stack
+		}
+		z.Tests = append(z.Tests, test)
+	}
+	return
+}
@@ -0,0 +1,161 @@
+// Package runner describes the e2e frameworks able to run specs
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Lord-Y/cypress-parallel-cli/executor"
+	"github.com/hashicorp/go-version"
+	"github.com/rs/zerolog/log"
+)
+
+// lookPath returns the path of the program in PATH
+var lookPath = exec.LookPath
+
+// DetectCypress returns the versions of the cypress package in PATH and of its binary.
+// The package version is read from the package.json of the cypress program found in PATH
+// or from node_modules/cypress of the project cloned in dir, binary, electron and node versions
+// are read from the binary cache. Versions that cannot be read are parsed from cypress --version
+func DetectCypress(ctx context.Context, e executor.Executor, dir string) (z Version, err error) {
+	z.Package = cypressPackageVersion(dir)
+
+	if z.Package != "" {
+		z.Binary, z.Electron, z.Node = cypressBinaryVersions(z.Package)
+	}
+
+	if z.Package == "" || z.Binary == "" || z.Electron == "" || z.Node == "" {
+		log.Debug().Msg("Cypress versions not found on disk, parsing cypress --version output")
+		output, err := executor.Output(ctx, e, executor.Command{Name: "cypress", Args: []string{"--version"}, Dir: dir, Probe: true})
+		if err != nil {
+			if z.Package == "" {
+				log.Error().Err(err).Msg("Error occured while running cypress version command")
+				return z, err
+			}
+			log.Warn().Err(err).Msg("Error occured while running cypress version command")
+		} else {
+			z.merge(parseCypressVersion(string(output)))
+		}
+	}
+
+	if z.Package == "" {
+		z.Package = z.Binary
+	}
+	if z.Package == "" {
+		err = fmt.Errorf("cypress version not found")
+		log.Error().Err(err).Msg("Error occured while detecting cypress version")
+		return
+	}
+	if _, err = version.NewVersion(z.Package); err != nil {
+		log.Error().Err(err).Msgf("Error occured while parsing cypress version %s", z.Package)
+		return
+	}
+	log.Debug().Msgf("Cypress package version %s, binary version %s, electron version %s, bundled node version %s", z.Package, z.Binary, z.Electron, z.Node)
+	return
+}
+
+// cypressPackageVersion returns the version of the cypress package in PATH
+// or installed in the project, empty when not found
+func cypressPackageVersion(dir string) string {
+	if v := PathCypressVersion(); v != "" {
+		return v
+	}
+	return readCypressPackage(filepath.Join(dir, "node_modules", "cypress", "package.json"))
+}
+
+// PathCypressVersion returns the version of the cypress package in PATH, empty when not found
+func PathCypressVersion() string {
+	program, err := lookPath("cypress")
+	if err != nil {
+		return ""
+	}
+	if program, err = filepath.EvalSymlinks(program); err != nil {
+		return ""
+	}
+	// npm links the program to node_modules/cypress/bin/cypress
+	return readCypressPackage(filepath.Join(filepath.Dir(filepath.Dir(program)), "package.json"))
+}
+
+// readCypressPackage returns the version of the cypress package.json file, empty when it is not one
+func readCypressPackage(file string) string {
+	var pkg struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	if err := json.Unmarshal(content, &pkg); err != nil || pkg.Name != "cypress" {
+		return ""
+	}
+	log.Debug().Msgf("Cypress package version %s read from %s", pkg.Version, file)
+	return pkg.Version
+}
+
+// cypressBinaryVersions returns the binary, electron and bundled node versions
+// of the binary matching the package version in the binary cache
+func cypressBinaryVersions(packageVersion string) (binary, electron, node string) {
+	cache := os.Getenv("CYPRESS_CACHE_FOLDER")
+	if cache == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return
+		}
+		cache = filepath.Join(home, ".cache", "Cypress")
+	}
+
+	var pkg struct {
+		Version             string `json:"version"`
+		ElectronVersion     string `json:"electronVersion"`
+		ElectronNodeVersion string `json:"electronNodeVersion"`
+	}
+	file := filepath.Join(cache, packageVersion, "Cypress", "resources", "app", "package.json")
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		log.Warn().Err(err).Msgf("Error occured while unmarshalling %s", file)
+		return
+	}
+	return pkg.Version, pkg.ElectronVersion, pkg.ElectronNodeVersion
+}
+
+// parseCypressVersion parses cypress --version output like
+//
+//	Cypress package version: 10.10.0
+//	Cypress binary version: 10.10.0
+//	Electron version: 19.0.8
+//	Bundled Node version:
+//	16.14.2
+func parseCypressVersion(output string) (z Version) {
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		// some versions print the bundled node version on the next line
+		if value == "" && i+1 < len(lines) && !strings.Contains(lines[i+1], ":") {
+			value = strings.TrimSpace(lines[i+1])
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "cypress package version":
+			z.Package = value
+		case "cypress binary version":
+			z.Binary = value
+		case "electron version":
+			z.Electron = value
+		case "bundled node version":
+			z.Node = value
+		}
+	}
+	return
+}
@@ -0,0 +1,155 @@
+// Package runner describes the e2e frameworks able to run specs
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Lord-Y/cypress-parallel-cli/executor"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCypressInstall installs a cypress package linked in PATH and its binary in the cache.
+// The binary is not installed when binary is empty
+func fakeCypressInstall(t *testing.T, packageVersion string, binary string) {
+	root := t.TempDir()
+	pkg := filepath.Join(root, "lib", "node_modules", "cypress")
+	bin := filepath.Join(root, "bin")
+	cache := filepath.Join(root, "cache")
+	for _, dir := range []string{filepath.Join(pkg, "bin"), bin, cache} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(pkg, "package.json"), []byte(fmt.Sprintf(`{"name": "cypress", "version": "%s"}`, packageVersion)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pkg, "bin", "cypress"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(pkg, "bin", "cypress"), filepath.Join(bin, "cypress")); err != nil {
+		t.Fatal(err)
+	}
+	if binary != "" {
+		app := filepath.Join(cache, packageVersion, "Cypress", "resources", "app")
+		if err := os.MkdirAll(app, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(app, "package.json"), []byte(binary), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin)
+	t.Setenv("CYPRESS_CACHE_FOLDER", cache)
+}
+
+func TestParseCypressVersion(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected Version
+	}{
+		{
+			output:   "Cypress package version: 10.10.0\nCypress binary version: 10.10.0\nElectron version: 19.0.8\nBundled Node version:\n16.14.2\n",
+			expected: Version{Package: "10.10.0", Binary: "10.10.0", Electron: "19.0.8", Node: "16.14.2"},
+		},
+		{
+			output:   "Cypress package version: 9.7.0\nCypress binary version: 9.7.0\nElectron version: 15.5.1\nBundled Node version: 16.13.2\n",
+			expected: Version{Package: "9.7.0", Binary: "9.7.0", Electron: "15.5.1", Node: "16.13.2"},
+		},
+		{
+			output:   "Cypress binary version: 12.3.0\n",
+			expected: Version{Binary: "12.3.0"},
+		},
+		{
+			output: "command not found\n",
+		},
+	}
+
+	assert := assert.New(t)
+	for _, tc := range tests {
+		assert.Equal(tc.expected, parseCypressVersion(tc.output))
+	}
+}
+
+func TestDetectCypress(t *testing.T) {
+	assert := assert.New(t)
+	fakeCypressInstall(t, "10.10.0", `{"version": "10.10.0", "electronVersion": "19.0.8", "electronNodeVersion": "16.14.2"}`)
+
+	r := &executor.Recorder{}
+	v, err := DetectCypress(context.Background(), r, t.TempDir())
+	assert.NoError(err)
+	assert.Equal(Version{Package: "10.10.0", Binary: "10.10.0", Electron: "19.0.8", Node: "16.14.2"}, v)
+	assert.Empty(r.Commands())
+}
+
+func TestDetectCypress_fallback(t *testing.T) {
+	tests := []struct {
+		install  bool
+		output   string
+		err      error
+		expected Version
+		fail     bool
+	}{
+		{
+			install:  true,
+			output:   "Cypress package version: 10.10.0\nCypress binary version: 10.10.1\nElectron version: 19.0.8\nBundled Node version:\n16.14.2\n",
+			expected: Version{Package: "10.10.0", Binary: "10.10.1", Electron: "19.0.8", Node: "16.14.2"},
+		},
+		{
+			install:  true,
+			err:      fmt.Errorf("exit status 1"),
+			expected: Version{Package: "10.10.0"},
+		},
+		{
+			output:   "Cypress binary version: 12.3.0\n",
+			expected: Version{Package: "12.3.0", Binary: "12.3.0"},
+		},
+		{
+			output: "Cypress package version: unknown\n",
+			fail:   true,
+		},
+		{
+			err:  fmt.Errorf("exit status 127"),
+			fail: true,
+		},
+	}
+
+	assert := assert.New(t)
+	for _, tc := range tests {
+		if tc.install {
+			fakeCypressInstall(t, "10.10.0", "")
+		} else {
+			t.Setenv("PATH", t.TempDir())
+			t.Setenv("CYPRESS_CACHE_FOLDER", t.TempDir())
+		}
+		r := &executor.Recorder{
+			Handler: func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+				return []byte(tc.output), tc.err
+			},
+		}
+		v, err := DetectCypress(context.Background(), r, t.TempDir())
+		assert.Equal([]string{"cypress --version"}, []string{r.Commands()[0].String()})
+		if tc.fail {
+			assert.Error(err)
+			continue
+		}
+		assert.NoError(err)
+		assert.Equal(tc.expected, v)
+	}
+}
+
+func TestDetectCypress_project(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("CYPRESS_CACHE_FOLDER", t.TempDir())
+	dir := t.TempDir()
+	assert.NoError(os.MkdirAll(filepath.Join(dir, "node_modules", "cypress"), 0755))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "node_modules", "cypress", "package.json"), []byte(`{"name": "cypress", "version": "9.7.0"}`), 0644))
+
+	v, err := DetectCypress(context.Background(), &executor.Recorder{}, dir)
+	assert.NoError(err)
+	assert.Equal(Version{Package: "9.7.0"}, v)
+}
@@ -0,0 +1,304 @@
+// Package runner describes the e2e frameworks able to run specs
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Lord-Y/cypress-parallel-cli/executor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCypress_install(t *testing.T) {
+	tests := []struct {
+		packages string
+		lockfile string
+		locked   string
+		image    string
+		expected []string
+	}{
+		{
+			packages: `{"devDependencies": {"cypress": "^10.0.0", "cypress-real-events": "1.7.0", "@cypress/grep": "3.1.0"}, "dependencies": {"lodash": "4.17.21"}}`,
+			image:    "12.0.0",
+			expected: []string{"npm uninstall cypress", "npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome"},
+		},
+		{
+			packages: `{"devDependencies": {"cypress": "^10.0.0", "cypress-real-events": "1.7.0"}}`,
+			image:    "10.10.0",
+			expected: []string{"npm install", "npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome"},
+		},
+		{
+			packages: `{"devDependencies": {"cypress": "latest"}}`,
+			image:    "10.10.0",
+			expected: []string{"npm uninstall cypress", "npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome"},
+		},
+		{
+			packages: `{"devDependencies": {"cypress": "12.0.0"}}`,
+			expected: []string{"npm install", "npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome"},
+		},
+		{
+			packages: `{"dependencies": {"lodash": "4.17.21"}}`,
+			image:    "10.10.0",
+			expected: []string{"npm install", "npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome"},
+		},
+		{
+			packages: `{"dependencies": {"lodash": "4.17.21"}}`,
+			lockfile: "package-lock.json",
+			expected: []string{"npm ci", "npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome"},
+		},
+		{
+			packages: `{"devDependencies": {"cypress": "^10.0.0"}}`,
+			lockfile: "package-lock.json",
+			locked:   `{"packages": {"node_modules/cypress": {"version": "10.3.0"}}}`,
+			image:    "10.10.0",
+			expected: []string{"npm uninstall cypress", "npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome"},
+		},
+		{
+			packages: `{"devDependencies": {"cypress": "^10.0.0"}}`,
+			lockfile: "package-lock.json",
+			locked:   `{"packages": {"node_modules/cypress": {"version": "10.10.0"}}}`,
+			image:    "10.10.0",
+			expected: []string{"npm ci", "npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome"},
+		},
+		{
+			packages: `{"devDependencies": {"cypress": "^10.0.0"}}`,
+			lockfile: "npm-shrinkwrap.json",
+			locked:   `{"dependencies": {"cypress": {"version": "10.3.0"}}}`,
+			image:    "10.10.0",
+			expected: []string{"npm uninstall cypress", "npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome"},
+		},
+		{
+			packages: `{"devDependencies": {"cypress": "~10.10.0", "cypress-real-events": "1.7.0"}}`,
+			lockfile: "yarn.lock",
+			image:    "10.11.0",
+			expected: []string{"yarn remove cypress", "npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome"},
+		},
+		{
+			packages: `{"dependencies": {"lodash": "4.17.21"}, "packageManager": "pnpm@8.6.0"}`,
+			lockfile: "pnpm-lock.yaml",
+			expected: []string{"pnpm install --frozen-lockfile", "npm install --prefix .cypress-parallel-cli --no-save --no-package-lock mochawesome"},
+		},
+	}
+
+	assert := assert.New(t)
+	for _, tc := range tests {
+		t.Setenv("PATH", t.TempDir())
+		if tc.image != "" {
+			fakeCypressInstall(t, tc.image, "")
+		}
+		dir := t.TempDir()
+		assert.NoError(os.WriteFile(filepath.Join(dir, "package.json"), []byte(tc.packages), 0644))
+		if tc.lockfile != "" {
+			assert.NoError(os.WriteFile(filepath.Join(dir, tc.lockfile), []byte(tc.locked), 0644))
+		}
+		r := &executor.Recorder{}
+		assert.NoError(Cypress{}.Install(context.Background(), r, dir))
+		var lines []string
+		for _, cmd := range r.Commands() {
+			assert.Equal(dir, cmd.Dir)
+			lines = append(lines, cmd.String())
+		}
+		assert.Equal(tc.expected, lines)
+		// the binary of the project package is only downloaded without cypress in the image
+		if tc.image != "" {
+			assert.Equal([]string{"CYPRESS_INSTALL_BINARY=0"}, r.Commands()[0].Env)
+		} else {
+			assert.Empty(r.Commands()[0].Env)
+		}
+	}
+
+	assert.Error(Cypress{}.Install(context.Background(), &executor.Recorder{}, t.TempDir()))
+}
+
+func TestCypressConflicts(t *testing.T) {
+	assert := assert.New(t)
+	// cypress in PATH not installed with npm
+	bin := t.TempDir()
+	assert.NoError(os.WriteFile(filepath.Join(bin, "cypress"), []byte("#!/bin/sh\n"), 0755))
+	t.Setenv("PATH", bin)
+	r := &executor.Recorder{
+		Handler: func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+			return []byte("Cypress package version: 10.10.0\nCypress binary version: 10.10.0\n"), nil
+		},
+	}
+	assert.False(cypressConflicts(context.Background(), r, "/tmp/project", ">=10.0.0 <11"))
+	assert.True(cypressConflicts(context.Background(), r, "/tmp/project", "^9.7.0"))
+	assert.Equal("cypress --version", r.Commands()[0].String())
+
+	r.Handler = func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+		return nil, fmt.Errorf("exit status 1")
+	}
+	assert.True(cypressConflicts(context.Background(), r, "/tmp/project", "^10.0.0"))
+}
+
+func TestCypress_version(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("CYPRESS_CACHE_FOLDER", t.TempDir())
+	r := &executor.Recorder{
+		Handler: func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+			return []byte("Cypress package version: 10.10.0\nCypress binary version: 10.10.0\n"), nil
+		},
+	}
+	v, err := Cypress{}.Version(context.Background(), r, "")
+	assert.NoError(err)
+	assert.Equal(Version{Package: "10.10.0", Binary: "10.10.0"}, v)
+
+	_, err = Cypress{}.Version(context.Background(), &executor.Recorder{}, "")
+	assert.Error(err)
+}
+
+func TestCypress_browsers(t *testing.T) {
+	assert := assert.New(t)
+	output := `Displaying Cypress info...
+
+Detected 2 browsers installed:
+
+1. Chrome
+  - Name: chrome
+  - Channel: stable
+  - Version: 106.0.5249.119
+  - Executable: /usr/bin/google-chrome
+
+2. Firefox
+  - Name: firefox
+  - Channel: stable
+  - Version: 105.0.3
+  - Executable: /usr/bin/firefox
+
+Note: to run these browsers, pass <name>:<channel> to the '--browser' field
+
+Cypress Version: 10.10.0 (stable)
+`
+	r := &executor.Recorder{
+		Handler: func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+			return []byte(output), nil
+		},
+	}
+	z, err := Cypress{}.Browsers(context.Background(), r, "/tmp/project")
+	assert.NoError(err)
+	assert.Equal([]string{"electron", "chrome", "chrome:stable", "firefox", "firefox:stable"}, z)
+	assert.Equal("cypress info", r.Commands()[0].String())
+	assert.Equal("/tmp/project", r.Commands()[0].Dir)
+
+	r = &executor.Recorder{
+		Handler: func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+			return nil, fmt.Errorf("exit status 1")
+		},
+	}
+	_, err = Cypress{}.Browsers(context.Background(), r, "/tmp/project")
+	assert.Error(err)
+}
+
+func TestCypress_command(t *testing.T) {
+	tests := []struct {
+		x          Execution
+		expected   string
+		resultFile string
+		fail       bool
+	}{
+		{
+			x:        Execution{Version: "10.10.0"},
+			expected: "cypress run --browser chrome --spec cypress/e2e/login.cy.js --reporter ./.cypress-parallel-cli/node_modules/mochawesome --reporter-options reportFilename=cypress_e2e_login",
+		},
+		{
+			x:        Execution{Version: "9.7.0"},
+			expected: "cypress run --browser chrome --headless --spec cypress/e2e/login.cy.js --reporter ./.cypress-parallel-cli/node_modules/mochawesome --reporter-options reportFilename=cypress_e2e_login",
+		},
+		{
+			x:        Execution{Version: "9.7.0", Headed: true},
+			expected: "cypress run --browser chrome --headed --spec cypress/e2e/login.cy.js --reporter ./.cypress-parallel-cli/node_modules/mochawesome --reporter-options reportFilename=cypress_e2e_login",
+		},
+		{
+			x: Execution{
+				Version:   "10.10.0",
+				Config:    "e2e/cypress.config.ts",
+				Env:       map[string]string{"user": "admin", "lang": "fr"},
+				Overrides: map[string]string{"baseUrl": "http://localhost:8080", "video": "false"},
+				Headed:    true,
+				Component: true,
+				Quiet:     true,
+			},
+			expected: "cypress run --browser chrome --headed --component --config-file e2e/cypress.config.ts --config baseUrl=http://localhost:8080,video=false --env lang=fr,user=admin --quiet --spec cypress/e2e/login.cy.js --reporter ./.cypress-parallel-cli/node_modules/mochawesome --reporter-options reportFilename=cypress_e2e_login",
+		},
+		{
+			x:        Execution{Version: "10.10.0", Overrides: map[string]string{"video": "true"}, Artifacts: "/tmp/project/.artifacts/cypress_e2e_login"},
+			expected: "cypress run --browser chrome --config screenshotsFolder=/tmp/project/.artifacts/cypress_e2e_login/screenshots,video=true,videosFolder=/tmp/project/.artifacts/cypress_e2e_login/videos --spec cypress/e2e/login.cy.js --reporter ./.cypress-parallel-cli/node_modules/mochawesome --reporter-options reportFilename=cypress_e2e_login",
+		},
+		{
+			x:        Execution{Version: "10.10.0", Env: map[string]string{"users": "admin,guest"}},
+			expected: `cypress run --browser chrome --env {"users":"admin,guest"} --spec cypress/e2e/login.cy.js --reporter ./.cypress-parallel-cli/node_modules/mochawesome --reporter-options reportFilename=cypress_e2e_login`,
+		},
+		{
+			x:          Execution{Version: "10.10.0", ReportDir: "/tmp/reports"},
+			expected:   "cypress run --browser chrome --spec cypress/e2e/login.cy.js --reporter ./.cypress-parallel-cli/node_modules/mochawesome --reporter-options reportFilename=cypress_e2e_login,reportDir=/tmp/reports",
+			resultFile: "/tmp/reports/cypress_e2e_login.json",
+		},
+		{
+			x:    Execution{Version: "unknown"},
+			fail: true,
+		},
+	}
+
+	assert := assert.New(t)
+	for _, tc := range tests {
+		x := tc.x
+		x.Dir = "/tmp/project"
+		x.Spec = "cypress/e2e/login.cy.js"
+		x.Browser = "chrome"
+		x.Report = "cypress_e2e_login"
+		cmd, err := Cypress{}.Command(x)
+		if tc.fail {
+			assert.Error(err)
+			continue
+		}
+		assert.NoError(err)
+		assert.Equal(tc.expected, cmd.String())
+		assert.Equal("/tmp/project", cmd.Dir)
+		if tc.resultFile == "" {
+			tc.resultFile = "/tmp/project/mochawesome-report/cypress_e2e_login.json"
+		}
+		assert.Equal(tc.resultFile, Cypress{}.ResultFile(x))
+	}
+
+	cmd, err := Cypress{}.Command(Execution{Version: "10.10.0", Spec: "cypress/e2e/login.cy.js", Report: "cypress_e2e_login"})
+	assert.NoError(err)
+	assert.Equal("cypress run --spec cypress/e2e/login.cy.js --reporter ./.cypress-parallel-cli/node_modules/mochawesome --reporter-options reportFilename=cypress_e2e_login", cmd.String())
+}
+
+func TestCypress_display(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("DISPLAY", "")
+	assert.True(Cypress{}.Display(Execution{Browser: "chrome"}))
+	assert.False(Cypress{}.Display(Execution{Browser: "electron"}))
+	assert.True(Cypress{}.Display(Execution{Browser: "electron", Headed: true}))
+}
+
+func TestCypress_parseResult(t *testing.T) {
+	assert := assert.New(t)
+	z, err := Cypress{}.ParseResult([]byte("{\n  \"stats\": {\n    \"tests\": 1\n  }\n}\n"))
+	assert.NoError(err)
+	assert.Equal(`{"stats":{"tests":1}}`, string(z.Content))
+	assert.Equal(&Stats{Tests: 1}, z.Stats)
+	assert.Empty(z.Tests)
+
+	z, err = Cypress{}.ParseResult([]byte(`{
+  "stats": {"suites": 1, "tests": 2, "passes": 1, "failures": 1},
+  "results": [{"title": "", "file": "cypress/e2e/login.cy.js", "tests": [], "suites": [{"title": "login", "tests": [
+    {"title": "logs in", "fullTitle": "login logs in", "duration": 120, "state": "passed", "pass": true, "code": "cy.login()", "err": {}},
+    {"title": "fails", "fullTitle": "login fails", "duration": 80, "state": "failed", "fail": true, "code": "cy.get('.error')", "err": {"message": "AssertionError: expected .error", "estack": "AssertionError: expected .error\n    at login.cy.js:3:9"}}
+  ], "suites": []}]}]
+}`))
+	assert.NoError(err)
+	assert.Equal(&Stats{Tests: 2, Passes: 1, Failures: 1}, z.Stats)
+	assert.Equal([]Test{
+		{Title: "login logs in", State: "passed", Duration: 120},
+		{Title: "login fails", State: "failed", Duration: 80, Error: "AssertionError: expected .error", Code: "cy.get('.error')", Stack: "AssertionError: expected .error\n    at login.cy.js:3:9"},
+	}, z.Tests)
+
+	_, err = Cypress{}.ParseResult([]byte("{"))
+	assert.Error(err)
+}
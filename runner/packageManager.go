@@ -0,0 +1,111 @@
+// Package runner describes the e2e frameworks able to run specs
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Lord-Y/cypress-parallel-cli/executor"
+	"github.com/hashicorp/go-version"
+)
+
+// Package managers
+const (
+	NPM  = "npm"
+	Yarn = "yarn"
+	PNPM = "pnpm"
+)
+
+// lockfiles of each package manager, the first one found on disk wins
+var lockfiles = []struct {
+	name     string
+	lockfile string
+}{
+	{name: PNPM, lockfile: "pnpm-lock.yaml"},
+	{name: Yarn, lockfile: "yarn.lock"},
+	{name: NPM, lockfile: "package-lock.json"},
+	{name: NPM, lockfile: "npm-shrinkwrap.json"},
+}
+
+// PackageManager installs the dependencies of a project
+type PackageManager struct {
+	Name     string // npm, yarn or pnpm
+	Version  string // Version set in the packageManager field of package.json, empty when not set
+	Lockfile string // Lockfile of the project, empty when there is none
+	Berry    bool   // Whether yarn is yarn 2 or later
+}
+
+// DetectPackageManager returns the package manager of the project cloned in dir.
+// The packageManager field of package.json wins over lockfiles, npm is used when none is found
+func DetectPackageManager(dir string) (z PackageManager, err error) {
+	var pkg struct {
+		PackageManager string `json:"packageManager"`
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(content, &pkg); err != nil {
+		return
+	}
+
+	if pkg.PackageManager != "" {
+		// formatted like pnpm@8.6.0 or yarn@3.6.1+sha224.abc
+		z.Name, z.Version, _ = strings.Cut(pkg.PackageManager, "@")
+		z.Version, _, _ = strings.Cut(z.Version, "+")
+		if z.Name != NPM && z.Name != Yarn && z.Name != PNPM {
+			return z, fmt.Errorf("unsupported package manager %s", pkg.PackageManager)
+		}
+	}
+	for _, l := range lockfiles {
+		if z.Name != "" && z.Name != l.name {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, l.lockfile)); err == nil {
+			z.Name = l.name
+			z.Lockfile = l.lockfile
+			break
+		}
+	}
+	if z.Name == "" {
+		z.Name = NPM
+	}
+
+	if z.Name == Yarn {
+		if v, err := version.NewVersion(z.Version); err == nil {
+			z.Berry = v.Segments()[0] >= 2
+		} else if _, err := os.Stat(filepath.Join(dir, ".yarnrc.yml")); err == nil {
+			z.Berry = true
+		}
+	}
+	return
+}
+
+// Install returns the command installing the dependencies of the project cloned in dir.
+// Dependencies are installed exactly from the lockfile when there is one
+func (p PackageManager) Install(dir string) executor.Command {
+	args := []string{"install"}
+	if p.Lockfile != "" {
+		switch {
+		case p.Name == NPM:
+			args = []string{"ci"}
+		case p.Berry:
+			args = append(args, "--immutable")
+		default:
+			args = append(args, "--frozen-lockfile")
+		}
+	}
+	return executor.Command{Name: p.Name, Args: args, Dir: dir}
+}
+
+// Remove returns the command removing the packages from the project cloned in dir
+func (p PackageManager) Remove(dir string, packages ...string) executor.Command {
+	command := "remove"
+	if p.Name == NPM {
+		command = "uninstall"
+	}
+	return executor.Command{Name: p.Name, Args: append([]string{command}, packages...), Dir: dir}
+}
@@ -0,0 +1,113 @@
+// Package runner describes the e2e frameworks able to run specs
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectPackageManager(t *testing.T) {
+	tests := []struct {
+		packages string
+		files    []string
+		expected PackageManager
+		install  string
+		remove   string
+		fail     bool
+	}{
+		{
+			packages: `{}`,
+			expected: PackageManager{Name: NPM},
+			install:  "npm install",
+			remove:   "npm uninstall cypress",
+		},
+		{
+			packages: `{}`,
+			files:    []string{"package-lock.json"},
+			expected: PackageManager{Name: NPM, Lockfile: "package-lock.json"},
+			install:  "npm ci",
+			remove:   "npm uninstall cypress",
+		},
+		{
+			packages: `{}`,
+			files:    []string{"npm-shrinkwrap.json"},
+			expected: PackageManager{Name: NPM, Lockfile: "npm-shrinkwrap.json"},
+			install:  "npm ci",
+			remove:   "npm uninstall cypress",
+		},
+		{
+			packages: `{}`,
+			files:    []string{"yarn.lock"},
+			expected: PackageManager{Name: Yarn, Lockfile: "yarn.lock"},
+			install:  "yarn install --frozen-lockfile",
+			remove:   "yarn remove cypress",
+		},
+		{
+			packages: `{}`,
+			files:    []string{"yarn.lock", ".yarnrc.yml"},
+			expected: PackageManager{Name: Yarn, Lockfile: "yarn.lock", Berry: true},
+			install:  "yarn install --immutable",
+			remove:   "yarn remove cypress",
+		},
+		{
+			packages: `{"packageManager": "yarn@3.6.1+sha224.953c8233f7a92884eee2de69a1b92d1f2ec1655e66d08071ba9a02fa"}`,
+			files:    []string{"yarn.lock"},
+			expected: PackageManager{Name: Yarn, Version: "3.6.1", Lockfile: "yarn.lock", Berry: true},
+			install:  "yarn install --immutable",
+			remove:   "yarn remove cypress",
+		},
+		{
+			packages: `{}`,
+			files:    []string{"pnpm-lock.yaml", "package-lock.json"},
+			expected: PackageManager{Name: PNPM, Lockfile: "pnpm-lock.yaml"},
+			install:  "pnpm install --frozen-lockfile",
+			remove:   "pnpm remove cypress",
+		},
+		{
+			packages: `{"packageManager": "pnpm@8.6.0"}`,
+			expected: PackageManager{Name: PNPM, Version: "8.6.0"},
+			install:  "pnpm install",
+			remove:   "pnpm remove cypress",
+		},
+		{
+			packages: `{"packageManager": "yarn@1.22.19"}`,
+			files:    []string{"package-lock.json", "yarn.lock"},
+			expected: PackageManager{Name: Yarn, Version: "1.22.19", Lockfile: "yarn.lock"},
+			install:  "yarn install --frozen-lockfile",
+			remove:   "yarn remove cypress",
+		},
+		{
+			packages: `{"packageManager": "bun@1.0.0"}`,
+			fail:     true,
+		},
+		{
+			packages: `{`,
+			fail:     true,
+		},
+	}
+
+	assert := assert.New(t)
+	for _, tc := range tests {
+		dir := t.TempDir()
+		assert.NoError(os.WriteFile(filepath.Join(dir, "package.json"), []byte(tc.packages), 0644))
+		for _, file := range tc.files {
+			assert.NoError(os.WriteFile(filepath.Join(dir, file), nil, 0644))
+		}
+		z, err := DetectPackageManager(dir)
+		if tc.fail {
+			assert.Error(err)
+			continue
+		}
+		assert.NoError(err)
+		assert.Equal(tc.expected, z)
+		assert.Equal(tc.install, z.Install(dir).String())
+		assert.Equal(dir, z.Install(dir).Dir)
+		assert.Equal(tc.remove, z.Remove(dir, "cypress").String())
+	}
+
+	_, err := DetectPackageManager(t.TempDir())
+	assert.Error(err)
+}
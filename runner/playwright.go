@@ -0,0 +1,145 @@
+// Package runner describes the e2e frameworks able to run specs
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Lord-Y/cypress-parallel-cli/executor"
+	"github.com/rs/zerolog/log"
+)
+
+// Playwright runs specs with the playwright version of the project and reports them with its json reporter
+type Playwright struct{}
+
+// Name returns the name of the framework
+func (Playwright) Name() string {
+	return "playwright"
+}
+
+// Install installs the project dependencies, playwright included, with its package manager
+func (Playwright) Install(ctx context.Context, e executor.Executor, dir string) error {
+	pm, err := DetectPackageManager(dir)
+	if err != nil {
+		log.Error().Err(err).Msg("Error occured while detecting package manager")
+		return err
+	}
+	output, err := executor.Output(ctx, e, pm.Install(dir))
+	log.Debug().Msgf("User packages install output %s", string(output))
+	if err != nil {
+		log.Error().Err(err).Msgf("Error occured while installing user packages")
+		return err
+	}
+	return nil
+}
+
+// Dependencies returns node_modules
+func (Playwright) Dependencies() []string {
+	return []string{"node_modules"}
+}
+
+// SpecNames returns the default names of playwright test files
+func (Playwright) SpecNames() []string {
+	return []string{"*.spec.*", "*.test.*"}
+}
+
+// Version returns the playwright version installed in the project,
+// it fails instead of downloading playwright when it is not installed yet
+func (Playwright) Version(ctx context.Context, e executor.Executor, dir string) (Version, error) {
+	output, err := executor.Output(ctx, e, executor.Command{Name: "npx", Args: []string{"--no-install", "playwright", "--version"}, Dir: dir, Probe: true})
+	if err != nil {
+		log.Error().Err(err).Msg("Error occured while running playwright version command")
+		return Version{}, err
+	}
+	// output is formatted like Version 1.40.1
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		err = fmt.Errorf("playwright version not found in %s", string(output))
+		log.Error().Err(err).Msg("Error occured while parsing playwright version command output")
+		return Version{}, err
+	}
+	return Version{Package: fields[len(fields)-1]}, nil
+}
+
+// Browsers returns nil as playwright projects are defined in the config of the project
+func (Playwright) Browsers(ctx context.Context, e executor.Executor, dir string) ([]string, error) {
+	return nil, nil
+}
+
+// Display returns true when the browser is headed and DISPLAY is not set
+func (Playwright) Display(x Execution) bool {
+	return x.Headed && os.Getenv("DISPLAY") == ""
+}
+
+// Command returns the playwright test command writing a json report.
+// Browser is the playwright project to run, all projects are run when empty.
+// Only one worker is used as specs are already run in parallel, screenshots, videos and traces are written in Artifacts when set.
+// Env is exposed as environment variables, component tests and config overrides are not supported
+func (Playwright) Command(x Execution) (executor.Command, error) {
+	if x.Component {
+		return executor.Command{}, fmt.Errorf("component tests are not supported by playwright runner")
+	}
+	if len(x.Overrides) > 0 {
+		return executor.Command{}, fmt.Errorf("config overrides are not supported by playwright runner")
+	}
+	args := []string{
+		"playwright",
+		"test",
+		x.Spec,
+		"--reporter",
+		"json",
+		"--workers",
+		"1",
+	}
+	if x.Browser != "" {
+		args = append(args, "--project", x.Browser)
+	}
+	if x.Config != "" {
+		args = append(args, "--config", x.Config)
+	}
+	if x.Headed {
+		args = append(args, "--headed")
+	}
+	if x.Quiet {
+		args = append(args, "--quiet")
+	}
+	if x.Artifacts != "" {
+		args = append(args, "--output", x.Artifacts)
+	}
+	env := []string{
+		fmt.Sprintf("NO_COLOR=%d", 1),
+		fmt.Sprintf("PLAYWRIGHT_JSON_OUTPUT_NAME=%s", Playwright{}.ResultFile(x)),
+	}
+	keys := make([]string, 0, len(x.Env))
+	for k := range x.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, fmt.Sprintf("%s=%s", k, x.Env[k]))
+	}
+	return executor.Command{
+		Name: "npx",
+		Args: args,
+		Dir:  x.Dir,
+		Env:  env,
+	}, nil
+}
+
+// ResultFile returns the json report of the execution
+func (Playwright) ResultFile(x Execution) string {
+	if x.ReportDir != "" {
+		return filepath.Join(x.ReportDir, x.Report+".json")
+	}
+	return filepath.Join(x.Dir, "playwright-report", x.Report+".json")
+}
+
+// ParseResult returns the compacted json report, tests are not parsed
+func (Playwright) ParseResult(content []byte) (z Result, err error) {
+	z.Content, err = compact(content)
+	return
+}
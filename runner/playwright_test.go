@@ -0,0 +1,118 @@
+// Package runner describes the e2e frameworks able to run specs
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Lord-Y/cypress-parallel-cli/executor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaywright_install(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	assert.NoError(os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"devDependencies": {"@playwright/test": "1.40.1"}}`), 0644))
+	r := &executor.Recorder{}
+	assert.NoError(Playwright{}.Install(context.Background(), r, dir))
+	assert.Len(r.Commands(), 1)
+	assert.Equal("npm install", r.Commands()[0].String())
+	assert.Equal(dir, r.Commands()[0].Dir)
+
+	assert.NoError(os.WriteFile(filepath.Join(dir, "pnpm-lock.yaml"), nil, 0644))
+	r = &executor.Recorder{}
+	assert.NoError(Playwright{}.Install(context.Background(), r, dir))
+	assert.Equal("pnpm install --frozen-lockfile", r.Commands()[0].String())
+
+	r.Handler = func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+		return nil, fmt.Errorf("exit status 1")
+	}
+	assert.Error(Playwright{}.Install(context.Background(), r, dir))
+	assert.Error(Playwright{}.Install(context.Background(), &executor.Recorder{}, t.TempDir()))
+}
+
+func TestPlaywright_version(t *testing.T) {
+	assert := assert.New(t)
+	r := &executor.Recorder{
+		Handler: func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+			return []byte("Version 1.40.1\n"), nil
+		},
+	}
+	v, err := Playwright{}.Version(context.Background(), r, "/tmp/project")
+	assert.NoError(err)
+	assert.Equal(Version{Package: "1.40.1"}, v)
+	assert.Equal("npx --no-install playwright --version", r.Commands()[0].String())
+
+	_, err = Playwright{}.Version(context.Background(), &executor.Recorder{}, "/tmp/project")
+	assert.Error(err)
+}
+
+func TestPlaywright_command(t *testing.T) {
+	tests := []struct {
+		browser  string
+		config   string
+		expected string
+	}{
+		{
+			browser:  "",
+			expected: "npx playwright test tests/login.spec.ts --reporter json --workers 1",
+		},
+		{
+			browser:  "firefox",
+			expected: "npx playwright test tests/login.spec.ts --reporter json --workers 1 --project firefox",
+		},
+		{
+			browser:  "webkit",
+			config:   "e2e/playwright.config.ts",
+			expected: "npx playwright test tests/login.spec.ts --reporter json --workers 1 --project webkit --config e2e/playwright.config.ts",
+		},
+	}
+
+	assert := assert.New(t)
+	for _, tc := range tests {
+		x := Execution{
+			Dir:     "/tmp/project",
+			Spec:    "tests/login.spec.ts",
+			Browser: tc.browser,
+			Config:  tc.config,
+			Version: "1.40.1",
+			Report:  "tests_login",
+		}
+		cmd, err := Playwright{}.Command(x)
+		assert.NoError(err)
+		assert.Equal(tc.expected, cmd.String())
+		assert.Equal("/tmp/project", cmd.Dir)
+		assert.Contains(cmd.Env, "PLAYWRIGHT_JSON_OUTPUT_NAME=/tmp/project/playwright-report/tests_login.json")
+		assert.Equal("/tmp/project/playwright-report/tests_login.json", Playwright{}.ResultFile(x))
+	}
+	assert.Equal("/tmp/reports/tests_login.json", Playwright{}.ResultFile(Execution{Dir: "/tmp/project", Report: "tests_login", ReportDir: "/tmp/reports"}))
+	t.Setenv("DISPLAY", "")
+	assert.False(Playwright{}.Display(Execution{Browser: "chromium"}))
+	assert.True(Playwright{}.Display(Execution{Browser: "chromium", Headed: true}))
+}
+
+func TestPlaywright_command_options(t *testing.T) {
+	assert := assert.New(t)
+	x := Execution{
+		Dir:    "/tmp/project",
+		Spec:   "tests/login.spec.ts",
+		Report: "tests_login",
+		Env:    map[string]string{"USER": "admin", "LANG": "fr"},
+		Headed: true,
+		Quiet:  true,
+
+		Artifacts: "/tmp/project/.artifacts/tests_login",
+	}
+	cmd, err := Playwright{}.Command(x)
+	assert.NoError(err)
+	assert.Equal("npx playwright test tests/login.spec.ts --reporter json --workers 1 --headed --quiet --output /tmp/project/.artifacts/tests_login", cmd.String())
+	assert.Equal([]string{"NO_COLOR=1", "PLAYWRIGHT_JSON_OUTPUT_NAME=/tmp/project/playwright-report/tests_login.json", "LANG=fr", "USER=admin"}, cmd.Env)
+
+	_, err = Playwright{}.Command(Execution{Spec: "tests/login.spec.ts", Component: true})
+	assert.Error(err)
+	_, err = Playwright{}.Command(Execution{Spec: "tests/login.spec.ts", Overrides: map[string]string{"baseURL": "http://localhost"}})
+	assert.Error(err)
+}
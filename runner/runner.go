@@ -0,0 +1,155 @@
+// Package runner describes the e2e frameworks able to run specs
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Lord-Y/cypress-parallel-cli/executor"
+	"github.com/Lord-Y/cypress-parallel-cli/logger"
+)
+
+// Runner is an e2e framework running specs one at a time
+type Runner interface {
+	// Name returns the name of the framework
+	Name() string
+	// Install installs the dependencies of the project cloned in dir and the reporter
+	Install(ctx context.Context, e executor.Executor, dir string) error
+	// Dependencies returns the directories written by Install, relative to the project,
+	// which are restored from the dependencies cache instead of running Install
+	Dependencies() []string
+	// SpecNames returns the glob patterns of spec file names, matched when a directory of specs is given
+	SpecNames() []string
+	// Version returns the versions of the framework used to run specs of the project cloned in dir
+	Version(ctx context.Context, e executor.Executor, dir string) (Version, error)
+	// Browsers returns the browsers installed on the host, nil when they cannot be listed
+	Browsers(ctx context.Context, e executor.Executor, dir string) ([]string, error)
+	// Display returns true when the execution needs an X display
+	Display(x Execution) bool
+	// Command returns the command running the spec of the execution
+	Command(x Execution) (executor.Command, error)
+	// ResultFile returns the path of the file in which the command writes its result
+	ResultFile(x Execution) string
+	// ParseResult checks the content of the result file and returns the result sent to the api
+	// with the outcome of its tests when the framework result is parsed
+	ParseResult(content []byte) (Result, error)
+}
+
+// Execution holds what is required to run a spec
+type Execution struct {
+	Dir     string // Directory of the cloned project
+	Spec    string // Spec to run
+	Browser string // Browser to use
+	Config  string // Relative path of the framework config, default one when empty
+	Version string // Package version of the framework returned by Runner.Version
+	Report  string // Name of the report, unique per spec and attempt
+
+	ReportDir string // Directory in which the result file is written, framework default in Dir when empty
+
+	Artifacts string // Directory in which screenshots and videos are written, framework default when empty
+
+	Env       map[string]string // Variables exposed to specs, like cypress --env
+	Overrides map[string]string // Values overriding the framework config, like cypress --config
+	Headed    bool              // Display the browser instead of running it headless
+	Component bool              // Run component tests instead of end to end tests
+	Quiet     bool              // Only print the output of the reporter
+}
+
+// Result is the result written by an execution
+type Result struct {
+	Content []byte // Compacted result sent to the api
+	Stats   *Stats // Aggregated stats of the tests, nil when the framework result is not parsed
+	Tests   []Test // Outcome of each test in declaration order
+}
+
+// Stats are the aggregated stats of tests
+type Stats struct {
+	Tests    int `json:"tests"`    // Number of tests
+	Passes   int `json:"passes"`   // Number of passed tests
+	Failures int `json:"failures"` // Number of failed tests and hooks
+	Pending  int `json:"pending"`  // Number of pending tests
+	Skipped  int `json:"skipped"`  // Number of tests skipped after a hook failure
+}
+
+// Add adds the stats of o
+func (s *Stats) Add(o Stats) {
+	s.Tests += o.Tests
+	s.Passes += o.Passes
+	s.Failures += o.Failures
+	s.Pending += o.Pending
+	s.Skipped += o.Skipped
+}
+
+// Test is the outcome of a test
+type Test struct {
+	Title    string `json:"title"`           // Title of the test prefixed by the titles of its suites
+	State    string `json:"state"`           // passed, failed, pending or skipped
+	Duration int64  `json:"duration"`        // Duration of the test in milliseconds
+	Error    string `json:"error,omitempty"` // Error message of failed tests
+	Code     string `json:"code,omitempty"`  // Code snippet of failed tests
+	Stack    string `json:"stack,omitempty"` // Stack trace of failed tests
+}
+
+// Version holds the versions of the framework, components unknown or not relevant are empty
+type Version struct {
+	Package  string `json:"package"`            // Version of the framework package
+	Binary   string `json:"binary,omitempty"`   // Version of the framework binary
+	Electron string `json:"electron,omitempty"` // Version of electron bundled with the binary
+	Node     string `json:"node,omitempty"`     // Version of node bundled with the binary
+}
+
+// String returns the package version
+func (v Version) String() string {
+	return v.Package
+}
+
+// merge sets the empty versions with the ones of o
+func (v *Version) merge(o Version) {
+	if v.Package == "" {
+		v.Package = o.Package
+	}
+	if v.Binary == "" {
+		v.Binary = o.Binary
+	}
+	if v.Electron == "" {
+		v.Electron = o.Electron
+	}
+	if v.Node == "" {
+		v.Node = o.Node
+	}
+}
+
+func init() {
+	logger.SetLoggerLogLevel()
+}
+
+// joinValues returns the values formatted like key1=value1,key2=value2 sorted by key,
+// or as a json object when a value contains a comma
+func joinValues(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for k, v := range values {
+		if strings.Contains(v, ",") {
+			z, _ := json.Marshal(values)
+			return string(z)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		keys[i] = fmt.Sprintf("%s=%s", k, values[k])
+	}
+	return strings.Join(keys, ",")
+}
+
+// compact returns the compacted json content
+func compact(content []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := json.Compact(buf, content); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,216 @@
+// Package runner describes the e2e frameworks able to run specs
+package runner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// partial is a version like 1.2.x whose missing or wildcard components are -1
+type partial [3]int
+
+// comparator matches versions compared to v with op, one of =, <, <=, > or >=
+type comparator struct {
+	op string
+	v  [3]int
+}
+
+// satisfies returns whether the version satisfies the npm range like ^10.2.0, ~10.2 || 11.x or 10.0.0 - 10.4.
+// Prerelease and build metadata are ignored, ranges which are not versions like tags or urls are errors
+func satisfies(npmRange string, v string) (bool, error) {
+	version, err := parsePartial(v)
+	if err != nil {
+		return false, err
+	}
+	if version[0] < 0 || version[1] < 0 || version[2] < 0 {
+		return false, fmt.Errorf("invalid version %s", v)
+	}
+
+	for _, alternative := range strings.Split(npmRange, "||") {
+		comparators, err := parseAlternative(alternative)
+		if err != nil {
+			return false, fmt.Errorf("invalid range %s: %w", npmRange, err)
+		}
+		ok := true
+		for _, c := range comparators {
+			ok = ok && c.match([3]int(version))
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// parseAlternative returns the comparators of a range without ||, all of them must match
+func parseAlternative(alternative string) (z []comparator, err error) {
+	fields := strings.Fields(alternative)
+	if len(fields) == 3 && fields[1] == "-" {
+		from, err := parsePartial(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		to, err := parsePartial(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		return append(desugar(">=", from), desugar("<=", to)...), nil
+	}
+
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		// operators may be separated from their version like >= 1.2.0
+		if strings.Trim(field, "<>=^~") == "" && i+1 < len(fields) {
+			i++
+			field += fields[i]
+		}
+		var op string
+		for _, candidate := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+			if strings.HasPrefix(field, candidate) {
+				op = candidate
+				break
+			}
+		}
+		p, err := parsePartial(strings.TrimPrefix(field, op))
+		if err != nil {
+			return nil, err
+		}
+		z = append(z, desugar(op, p)...)
+	}
+	return
+}
+
+// parsePartial parses versions like 1.2.3, v1.2, 1.x or *
+func parsePartial(s string) (z partial, err error) {
+	z = partial{-1, -1, -1}
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "="), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	if s == "" || s == "*" || s == "x" || s == "X" {
+		return
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return z, fmt.Errorf("invalid version %s", s)
+	}
+	for i, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return z, fmt.Errorf("invalid version %s", s)
+		}
+		z[i] = n
+	}
+	return
+}
+
+// desugar returns the comparators matching the operator applied to the partial version,
+// like ^1.2 which matches >=1.2.0 <2.0.0. No comparator means any version
+func desugar(op string, p partial) []comparator {
+	never := []comparator{{op: "<", v: [3]int{0, 0, 0}}}
+	filled := [3]int{}
+	for i, n := range p {
+		if n > 0 {
+			filled[i] = n
+		}
+	}
+	// next returns the first version after the components of p up to i
+	next := func(i int) (z [3]int) {
+		copy(z[:i+1], filled[:i+1])
+		z[i]++
+		return
+	}
+
+	switch op {
+	case "", "=":
+		switch {
+		case p[0] < 0:
+			return nil
+		case p[1] < 0:
+			return []comparator{{">=", filled}, {"<", next(0)}}
+		case p[2] < 0:
+			return []comparator{{">=", filled}, {"<", next(1)}}
+		}
+		return []comparator{{"=", filled}}
+	case "^":
+		switch {
+		case p[0] < 0:
+			return nil
+		case p[0] > 0 || p[1] < 0:
+			return []comparator{{">=", filled}, {"<", next(0)}}
+		case p[1] > 0 || p[2] < 0:
+			return []comparator{{">=", filled}, {"<", next(1)}}
+		}
+		return []comparator{{">=", filled}, {"<", next(2)}}
+	case "~":
+		switch {
+		case p[0] < 0:
+			return nil
+		case p[1] < 0:
+			return []comparator{{">=", filled}, {"<", next(0)}}
+		}
+		return []comparator{{">=", filled}, {"<", next(1)}}
+	case ">":
+		switch {
+		case p[0] < 0:
+			return never
+		case p[1] < 0:
+			return []comparator{{">=", next(0)}}
+		case p[2] < 0:
+			return []comparator{{">=", next(1)}}
+		}
+		return []comparator{{">", filled}}
+	case ">=":
+		if p[0] < 0 {
+			return nil
+		}
+		return []comparator{{">=", filled}}
+	case "<":
+		if p[0] < 0 {
+			return never
+		}
+		return []comparator{{"<", filled}}
+	case "<=":
+		switch {
+		case p[0] < 0:
+			return nil
+		case p[1] < 0:
+			return []comparator{{"<", next(0)}}
+		case p[2] < 0:
+			return []comparator{{"<", next(1)}}
+		}
+		return []comparator{{"<=", filled}}
+	}
+	return never
+}
+
+// match returns whether v matches the comparator
+func (c comparator) match(v [3]int) bool {
+	cmp := 0
+	for i := range v {
+		if v[i] != c.v[i] {
+			cmp = 1
+			if v[i] < c.v[i] {
+				cmp = -1
+			}
+			break
+		}
+	}
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+// Package runner describes the e2e frameworks able to run specs
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		npmRange string
+		version  string
+		expected bool
+		fail     bool
+	}{
+		{npmRange: "10.10.0", version: "10.10.0", expected: true},
+		{npmRange: "=v10.10.0", version: "10.10.0", expected: true},
+		{npmRange: "10.10.0", version: "10.10.1"},
+		{npmRange: "10", version: "10.11.0", expected: true},
+		{npmRange: "10.x", version: "11.0.0"},
+		{npmRange: "10.10", version: "10.10.3", expected: true},
+		{npmRange: "10.10.x", version: "10.11.0"},
+		{npmRange: "*", version: "12.0.0", expected: true},
+		{npmRange: "", version: "12.0.0", expected: true},
+		{npmRange: "^10.2.0", version: "10.11.0", expected: true},
+		{npmRange: "^10.2.0", version: "10.1.0"},
+		{npmRange: "^10.2.0", version: "11.0.0"},
+		{npmRange: "^0.2.3", version: "0.2.9", expected: true},
+		{npmRange: "^0.2.3", version: "0.3.0"},
+		{npmRange: "^0.0.3", version: "0.0.4"},
+		{npmRange: "^0.x", version: "0.9.0", expected: true},
+		{npmRange: "~10.2.1", version: "10.2.9", expected: true},
+		{npmRange: "~10.2.1", version: "10.3.0"},
+		{npmRange: "~10", version: "10.9.0", expected: true},
+		{npmRange: ">10.2", version: "10.2.9"},
+		{npmRange: ">10.2", version: "10.3.0", expected: true},
+		{npmRange: ">= 10.2.0 < 11", version: "10.11.0", expected: true},
+		{npmRange: ">=10.2.0 <11", version: "11.0.0"},
+		{npmRange: "<=10.2", version: "10.2.9", expected: true},
+		{npmRange: "<=10.2", version: "10.3.0"},
+		{npmRange: "<10.2", version: "10.2.0"},
+		{npmRange: "10.0.0 - 10.4", version: "10.4.5", expected: true},
+		{npmRange: "10.0.0 - 10.4", version: "10.5.0"},
+		{npmRange: "^9.7.0 || ^10.2.0", version: "10.3.0", expected: true},
+		{npmRange: "^9.7.0 || ^10.2.0", version: "10.1.0"},
+		{npmRange: "^10.0.0-beta.1", version: "10.0.0", expected: true},
+		{npmRange: "latest", version: "10.10.0", fail: true},
+		{npmRange: "file:../cypress", version: "10.10.0", fail: true},
+		{npmRange: "npm:cypress@10.10.0", version: "10.10.0", fail: true},
+		{npmRange: "^10.0.0", version: "10.10", fail: true},
+	}
+
+	assert := assert.New(t)
+	for _, tc := range tests {
+		z, err := satisfies(tc.npmRange, tc.version)
+		if tc.fail {
+			assert.Error(err, tc.npmRange)
+			continue
+		}
+		assert.NoError(err, tc.npmRange)
+		assert.Equal(tc.expected, z, tc.npmRange+" "+tc.version)
+	}
+}
@@ -0,0 +1,127 @@
+// Package specs resolves the list of specs to run from files, directories and glob patterns
+package specs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Manifest holds settings overridden for some specs
+type Manifest struct {
+	Specs []Settings `json:"specs"`
+}
+
+// Settings are the settings of the specs matching Pattern
+type Settings struct {
+	Pattern   string   `json:"pattern"`             // Spec path or glob pattern relative to the repository root
+	Timeout   Duration `json:"timeout,omitempty"`   // Maximum duration of each attempt of the spec
+	Env       Values   `json:"env,omitempty"`       // Variables exposed to the spec, merged with --env
+	Config    Values   `json:"config,omitempty"`    // Config overrides of the spec, merged with --config
+	Headed    *bool    `json:"headed,omitempty"`    // Display the browser instead of running it headless
+	Component *bool    `json:"component,omitempty"` // Run component tests instead of end to end tests
+	Quiet     *bool    `json:"quiet,omitempty"`     // Only print the output of the reporter
+}
+
+// Values are key value pairs, numbers and booleans are accepted in json and converted to strings
+type Values map[string]string
+
+// UnmarshalJSON parses an object of strings, numbers and booleans
+func (v *Values) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*v = make(Values, len(raw))
+	for key, value := range raw {
+		switch value := value.(type) {
+		case string:
+			(*v)[key] = value
+		case float64, bool:
+			(*v)[key] = fmt.Sprint(value)
+		default:
+			return fmt.Errorf("value of %s must be a string, a number or a boolean", key)
+		}
+	}
+	return nil
+}
+
+// Duration is a time.Duration formatted in json like 90s or 5m
+type Duration time.Duration
+
+// UnmarshalJSON parses durations like 90s or 5m
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like 90s or 5m: %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON formats the duration like 1m30s
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// ReadManifest returns the manifest of the json file
+func ReadManifest(file string) (z Manifest, err error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&z); err != nil {
+		return z, fmt.Errorf("invalid manifest %s: %w", file, err)
+	}
+	for _, s := range z.Specs {
+		if s.Pattern == "" {
+			return z, fmt.Errorf("invalid manifest %s: pattern is required", file)
+		}
+	}
+	return
+}
+
+// Settings returns the settings of the spec.
+// When several entries match the spec, the last one setting a field wins,
+// env and config values are merged key by key
+func (m Manifest) Settings(spec string) (z Settings) {
+	z.Pattern = spec
+	for _, s := range m.Specs {
+		if len(match([]string{spec}, s.Pattern)) == 0 {
+			continue
+		}
+		if s.Timeout != 0 {
+			z.Timeout = s.Timeout
+		}
+		for key, value := range s.Env {
+			if z.Env == nil {
+				z.Env = make(Values)
+			}
+			z.Env[key] = value
+		}
+		for key, value := range s.Config {
+			if z.Config == nil {
+				z.Config = make(Values)
+			}
+			z.Config[key] = value
+		}
+		if s.Headed != nil {
+			z.Headed = s.Headed
+		}
+		if s.Component != nil {
+			z.Component = s.Component
+		}
+		if s.Quiet != nil {
+			z.Quiet = s.Quiet
+		}
+	}
+	return
+}
@@ -0,0 +1,122 @@
+// Package specs resolves the list of specs to run from files, directories and glob patterns
+package specs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadManifest(t *testing.T) {
+	tests := []struct {
+		content string
+		fail    bool
+	}{
+		{
+			content: `{"specs": [{"pattern": "cypress/e2e/slow/**", "timeout": "20m"}]}`,
+		},
+		{
+			content: `{"specs": [{"pattern": "cypress/e2e/slow/**", "timeout": 20}]}`,
+			fail:    true,
+		},
+		{
+			content: `{"specs": [{"pattern": "cypress/e2e/slow/**", "timeout": "20 minutes"}]}`,
+			fail:    true,
+		},
+		{
+			content: `{"specs": [{"timeout": "20m"}]}`,
+			fail:    true,
+		},
+		{
+			content: `{"specs": [{"pattern": "cypress/e2e/slow/**", "unknown": true}]}`,
+			fail:    true,
+		},
+	}
+
+	assert := assert.New(t)
+	for _, tc := range tests {
+		file := filepath.Join(t.TempDir(), "manifest.json")
+		assert.NoError(os.WriteFile(file, []byte(tc.content), 0644))
+		z, err := ReadManifest(file)
+		if tc.fail {
+			assert.Error(err)
+			continue
+		}
+		assert.NoError(err)
+		assert.Equal(Duration(20*time.Minute), z.Specs[0].Timeout)
+	}
+
+	_, err := ReadManifest(filepath.Join(t.TempDir(), "manifest.json"))
+	assert.Error(err)
+}
+
+func TestReadManifest_values(t *testing.T) {
+	assert := assert.New(t)
+	file := filepath.Join(t.TempDir(), "manifest.json")
+	assert.NoError(os.WriteFile(file, []byte(`{"specs": [{"pattern": "**", "env": {"user": "admin", "retries": 2, "mock": true}, "headed": false}]}`), 0644))
+	z, err := ReadManifest(file)
+	assert.NoError(err)
+	assert.Equal(Values{"user": "admin", "retries": "2", "mock": "true"}, z.Specs[0].Env)
+	assert.NotNil(z.Specs[0].Headed)
+	assert.False(*z.Specs[0].Headed)
+
+	assert.NoError(os.WriteFile(file, []byte(`{"specs": [{"pattern": "**", "config": {"viewport": {"width": 1280}}}]}`), 0644))
+	_, err = ReadManifest(file)
+	assert.Error(err)
+}
+
+func TestManifest_settings_values(t *testing.T) {
+	assert := assert.New(t)
+	headed, headless := true, false
+	m := Manifest{
+		Specs: []Settings{
+			{Pattern: "cypress/**", Env: Values{"user": "admin", "lang": "en"}, Headed: &headed},
+			{Pattern: "cypress/e2e/fr/**", Env: Values{"lang": "fr"}, Config: Values{"baseUrl": "https://fr.example.com"}, Headed: &headless},
+		},
+	}
+
+	z := m.Settings("cypress/e2e/fr/login.cy.js")
+	assert.Equal(Values{"user": "admin", "lang": "fr"}, z.Env)
+	assert.Equal(Values{"baseUrl": "https://fr.example.com"}, z.Config)
+	assert.False(*z.Headed)
+	assert.Nil(z.Component)
+
+	z = m.Settings("cypress/e2e/login.cy.js")
+	assert.Equal(Values{"user": "admin", "lang": "en"}, z.Env)
+	assert.Nil(z.Config)
+	assert.True(*z.Headed)
+}
+
+func TestManifest_settings(t *testing.T) {
+	m := Manifest{
+		Specs: []Settings{
+			{Pattern: "cypress/e2e/**/*.cy.js", Timeout: Duration(5 * time.Minute)},
+			{Pattern: "cypress/e2e/slow/**", Timeout: Duration(20 * time.Minute)},
+			{Pattern: "cypress/e2e/slow/upload.cy.js"},
+		},
+	}
+	tests := []struct {
+		spec     string
+		expected time.Duration
+	}{
+		{
+			spec:     "cypress/e2e/login.cy.js",
+			expected: 5 * time.Minute,
+		},
+		{
+			spec:     "cypress/e2e/slow/upload.cy.js",
+			expected: 20 * time.Minute,
+		},
+		{
+			spec: "cypress/component/button.cy.ts",
+		},
+	}
+
+	assert := assert.New(t)
+	for _, tc := range tests {
+		assert.Equal(tc.expected, time.Duration(m.Settings(tc.spec).Timeout))
+	}
+}
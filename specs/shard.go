@@ -0,0 +1,57 @@
+// Package specs resolves the list of specs to run from files, directories and glob patterns
+package specs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Shard is the portion of specs run by one of several executions
+type Shard struct {
+	Index int // Index of the shard starting at 1
+	Total int // Total number of shards
+}
+
+// ParseShard returns the shard described by index/total like 2/5
+func ParseShard(s string) (z Shard, err error) {
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	if len(parts) != 2 {
+		return z, fmt.Errorf("invalid shard %s, expected format is index/total", s)
+	}
+	z.Index, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return z, fmt.Errorf("invalid shard index %s: %w", parts[0], err)
+	}
+	z.Total, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return z, fmt.Errorf("invalid shard total %s: %w", parts[1], err)
+	}
+	if z.Total < 1 || z.Index < 1 || z.Index > z.Total {
+		return z, fmt.Errorf("invalid shard %s, index must be between 1 and total", s)
+	}
+	return
+}
+
+// String returns the shard formatted as index/total
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Total)
+}
+
+// Split returns the specs assigned to the shard.
+// Specs are sorted before being distributed in turn to each shard
+// so every execution computes the same split
+func (s Shard) Split(specs []string) (z []string) {
+	sorted := make([]string, len(specs))
+	copy(sorted, specs)
+	sort.Strings(sorted)
+
+	z = []string{}
+	for i, spec := range sorted {
+		if i%s.Total == s.Index-1 {
+			z = append(z, spec)
+		}
+	}
+	return
+}
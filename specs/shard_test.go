@@ -0,0 +1,65 @@
+// Package specs resolves the list of specs to run from files, directories and glob patterns
+package specs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseShard(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		shard    string
+		expected Shard
+		fail     bool
+	}{
+		{
+			shard:    "2/5",
+			expected: Shard{Index: 2, Total: 5},
+		},
+		{
+			shard:    " 1/1 ",
+			expected: Shard{Index: 1, Total: 1},
+		},
+		{
+			shard: "0/5",
+			fail:  true,
+		},
+		{
+			shard: "6/5",
+			fail:  true,
+		},
+		{
+			shard: "a/5",
+			fail:  true,
+		},
+		{
+			shard: "2",
+			fail:  true,
+		},
+	}
+
+	for _, tc := range tests {
+		z, err := ParseShard(tc.shard)
+		if tc.fail {
+			assert.Error(err)
+		} else {
+			assert.NoError(err)
+			assert.Equal(tc.expected, z)
+			assert.Equal(strings.TrimSpace(tc.shard), z.String())
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	assert := assert.New(t)
+	specs := []string{"e.cy.js", "a.cy.js", "d.cy.js", "b.cy.js", "c.cy.js"}
+
+	assert.Equal([]string{"a.cy.js", "c.cy.js", "e.cy.js"}, Shard{Index: 1, Total: 2}.Split(specs))
+	assert.Equal([]string{"b.cy.js", "d.cy.js"}, Shard{Index: 2, Total: 2}.Split(specs))
+	assert.Equal([]string{}, Shard{Index: 7, Total: 7}.Split(specs))
+	assert.Equal([]string{"e.cy.js", "a.cy.js", "d.cy.js", "b.cy.js", "c.cy.js"}, specs)
+}
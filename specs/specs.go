@@ -0,0 +1,216 @@
+// Package specs resolves the list of specs to run from files, directories and glob patterns
+package specs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Lord-Y/cypress-parallel-cli/logger"
+	"github.com/rs/zerolog/log"
+)
+
+// extensions are the file extensions considered as specs when a directory is given
+var extensions = []string{".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".coffee"}
+
+// ignoredDirs are directories never walked while resolving specs
+var ignoredDirs = []string{"node_modules", ".git"}
+
+func init() {
+	logger.SetLoggerLogLevel()
+}
+
+// Expand returns the deduplicated list of specs matching the patterns.
+// Patterns are relative to dir and can be files, directories or glob patterns
+// supporting ** and {a,b} alternatives. Directories are expanded to the files matching
+// one of names, like *.cy.*, so support files and helpers are not run.
+// Files that do not exist are kept as is so cypress can report them
+func Expand(dir string, patterns []string, names []string) (z []string, err error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(spec string) {
+		if !seen[spec] {
+			seen[spec] = true
+			z = append(z, spec)
+		}
+	}
+
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		pattern = path.Clean(filepath.ToSlash(pattern))
+
+		if !isGlob(pattern) {
+			info, err := os.Stat(filepath.Join(dir, pattern))
+			if err != nil || !info.IsDir() {
+				add(pattern)
+				continue
+			}
+			pattern = path.Join(pattern, "**")
+			if len(names) > 0 {
+				pattern = path.Join(pattern, "{"+strings.Join(names, ",")+"}")
+			}
+		}
+
+		if files == nil {
+			files, err = walk(dir)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		matches := match(files, pattern)
+		if len(matches) == 0 {
+			log.Warn().Msgf("No spec found matching %s", pattern)
+		}
+		for _, spec := range matches {
+			add(spec)
+		}
+	}
+	return
+}
+
+// SplitPatterns returns the patterns of a comma separated list.
+// Commas inside {a,b} alternatives do not separate patterns
+func SplitPatterns(list string) (z []string) {
+	depth := 0
+	last := 0
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				z = append(z, list[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return append(z, list[last:])
+}
+
+// isGlob returns true when the pattern contains glob special characters
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[{")
+}
+
+// walk returns the sorted list of spec files found in dir relative to it
+func walk(dir string) (z []string, err error) {
+	z = []string{}
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			for _, ignored := range ignoredDirs {
+				if d.Name() == ignored {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		// type declarations are never specs
+		if strings.HasSuffix(d.Name(), ".d.ts") {
+			return nil
+		}
+		for _, ext := range extensions {
+			if strings.HasSuffix(d.Name(), ext) {
+				rel, err := filepath.Rel(dir, p)
+				if err != nil {
+					return err
+				}
+				z = append(z, filepath.ToSlash(rel))
+				break
+			}
+		}
+		return nil
+	})
+	sort.Strings(z)
+	return
+}
+
+// match returns the files matching the pattern
+func match(files []string, pattern string) (z []string) {
+	var patterns [][]string
+	for _, p := range expandBraces(pattern) {
+		patterns = append(patterns, strings.Split(p, "/"))
+	}
+
+	for _, file := range files {
+		segments := strings.Split(file, "/")
+		for _, p := range patterns {
+			if matchSegments(p, segments) {
+				z = append(z, file)
+				break
+			}
+		}
+	}
+	return
+}
+
+// matchSegments returns true when path segments match pattern segments.
+// ** matches zero or more segments
+func matchSegments(pattern []string, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], segments[0])
+	if err != nil || !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// expandBraces returns all the patterns described by {a,b} alternatives
+func expandBraces(pattern string) (z []string) {
+	start := strings.Index(pattern, "{")
+	if start < 0 {
+		return []string{pattern}
+	}
+
+	depth := 0
+	var alternatives []string
+	last := start + 1
+	for i := start; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case ',':
+			if depth == 1 {
+				alternatives = append(alternatives, pattern[last:i])
+				last = i + 1
+			}
+		case '}':
+			depth--
+			if depth == 0 {
+				alternatives = append(alternatives, pattern[last:i])
+				for _, alternative := range alternatives {
+					z = append(z, expandBraces(pattern[:start]+alternative+pattern[i+1:])...)
+				}
+				return
+			}
+		}
+	}
+	// unbalanced braces are matched literally
+	return []string{pattern}
+}
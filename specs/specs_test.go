@@ -0,0 +1,186 @@
+// Package specs resolves the list of specs to run from files, directories and glob patterns
+package specs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTree(t *testing.T) string {
+	dir := t.TempDir()
+	for _, file := range []string{
+		"cypress/e2e/login.cy.js",
+		"cypress/e2e/admin/users.cy.ts",
+		"cypress/e2e/admin/roles.cy.js",
+		"cypress/e2e/admin/README.md",
+		"cypress/support/e2e.js",
+		"cypress/e2e/admin/helpers.js",
+		"cypress/e2e/index.d.ts",
+		"tests/login.spec.ts",
+		"tests/fixtures.ts",
+		"node_modules/cypress/index.cy.js",
+	} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(""), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestExpand(t *testing.T) {
+	assert := assert.New(t)
+	dir := createTree(t)
+
+	tests := []struct {
+		patterns []string
+		names    []string
+		expected []string
+	}{
+		{
+			patterns: []string{"cypress/e2e/login.cy.js"},
+			expected: []string{"cypress/e2e/login.cy.js"},
+		},
+		{
+			patterns: []string{"cypress/e2e/missing.cy.js"},
+			expected: []string{"cypress/e2e/missing.cy.js"},
+		},
+		{
+			patterns: []string{"cypress/e2e/**/*.cy.{js,ts}"},
+			expected: []string{
+				"cypress/e2e/admin/roles.cy.js",
+				"cypress/e2e/admin/users.cy.ts",
+				"cypress/e2e/login.cy.js",
+			},
+		},
+		{
+			patterns: []string{"cypress/e2e/admin"},
+			names:    []string{"*.cy.*"},
+			expected: []string{
+				"cypress/e2e/admin/roles.cy.js",
+				"cypress/e2e/admin/users.cy.ts",
+			},
+		},
+		{
+			patterns: []string{"cypress"},
+			names:    []string{"*.cy.*"},
+			expected: []string{
+				"cypress/e2e/admin/roles.cy.js",
+				"cypress/e2e/admin/users.cy.ts",
+				"cypress/e2e/login.cy.js",
+			},
+		},
+		{
+			patterns: []string{"tests"},
+			names:    []string{"*.spec.*", "*.test.*"},
+			expected: []string{"tests/login.spec.ts"},
+		},
+		{
+			patterns: []string{"cypress/e2e"},
+			expected: []string{
+				"cypress/e2e/admin/helpers.js",
+				"cypress/e2e/admin/roles.cy.js",
+				"cypress/e2e/admin/users.cy.ts",
+				"cypress/e2e/login.cy.js",
+			},
+		},
+		{
+			patterns: []string{"cypress/e2e/login.cy.js", "./cypress/e2e/*.cy.js", " cypress/e2e/**/*.cy.js "},
+			expected: []string{
+				"cypress/e2e/login.cy.js",
+				"cypress/e2e/admin/roles.cy.js",
+			},
+		},
+		{
+			patterns: []string{"**/*.cy.js"},
+			expected: []string{
+				"cypress/e2e/admin/roles.cy.js",
+				"cypress/e2e/login.cy.js",
+			},
+		},
+		{
+			patterns: []string{"cypress/e2e/*.cy.ts", ""},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range tests {
+		z, err := Expand(dir, tc.patterns, tc.names)
+		assert.NoError(err)
+		assert.Equal(tc.expected, z)
+	}
+}
+
+func TestSplitPatterns(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		list     string
+		expected []string
+	}{
+		{
+			list:     "a.cy.js",
+			expected: []string{"a.cy.js"},
+		},
+		{
+			list:     "a.cy.js,b/*.cy.js",
+			expected: []string{"a.cy.js", "b/*.cy.js"},
+		},
+		{
+			list:     "cypress/e2e/**/*.cy.{js,ts}",
+			expected: []string{"cypress/e2e/**/*.cy.{js,ts}"},
+		},
+		{
+			list:     "{a,b}/*.{cy.{js,ts},spec.js},c.cy.js",
+			expected: []string{"{a,b}/*.{cy.{js,ts},spec.js}", "c.cy.js"},
+		},
+		{
+			list:     "a}.cy.js,b.cy.js",
+			expected: []string{"a}.cy.js", "b.cy.js"},
+		},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(tc.expected, SplitPatterns(tc.list))
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{
+			pattern:  "a/*.js",
+			expected: []string{"a/*.js"},
+		},
+		{
+			pattern:  "a/*.{js,ts}",
+			expected: []string{"a/*.js", "a/*.ts"},
+		},
+		{
+			pattern:  "{a,b}/*.{js,ts}",
+			expected: []string{"a/*.js", "a/*.ts", "b/*.js", "b/*.ts"},
+		},
+		{
+			pattern:  "a/*.{cy.{js,ts},spec.js}",
+			expected: []string{"a/*.cy.js", "a/*.cy.ts", "a/*.spec.js"},
+		},
+		{
+			pattern:  "a/{b",
+			expected: []string{"a/{b"},
+		},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(tc.expected, expandBraces(tc.pattern))
+	}
+}
@@ -0,0 +1,125 @@
+// Package specs resolves the list of specs to run from files, directories and glob patterns
+package specs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Lord-Y/cypress-parallel-cli/httprequests"
+)
+
+// Timings are the last known durations of specs in milliseconds
+type Timings map[string]int64
+
+// ReadTimings returns the timings from a local json file or an HTTP(s) url.
+// A local file that does not exist returns empty timings
+func ReadTimings(source string) (z Timings, err error) {
+	var content []byte
+	z = make(Timings)
+
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		headers := make(map[string]string)
+		headers["Accept"] = "application/json"
+		body, resp, err := httprequests.PerformRequests(headers, "GET", source, "", "")
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status code %d while fetching timings from %s", resp.StatusCode, source)
+		}
+		content = body
+	} else {
+		content, err = os.ReadFile(source)
+		if os.IsNotExist(err) {
+			return z, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = json.Unmarshal(content, &z); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Write atomically writes timings into a json file
+func (t Timings) Write(path string) (err error) {
+	content, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(append(content, '\n')); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Balance returns the specs assigned to the shard using their timings.
+// Specs are sorted from the longest to the shortest and each one is assigned
+// to the shard with the lowest total duration so far.
+// Specs without timing are considered as long as the average known duration
+func (s Shard) Balance(specs []string, timings Timings) (z []string) {
+	var (
+		total int64
+		known int64
+	)
+	for _, spec := range specs {
+		if d, ok := timings[spec]; ok {
+			total += d
+			known++
+		}
+	}
+	average := int64(1)
+	if known > 0 {
+		average = total / known
+	}
+
+	durations := make(map[string]int64)
+	sorted := make([]string, len(specs))
+	copy(sorted, specs)
+	for _, spec := range sorted {
+		durations[spec] = average
+		if d, ok := timings[spec]; ok {
+			durations[spec] = d
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if durations[sorted[i]] == durations[sorted[j]] {
+			return sorted[i] < sorted[j]
+		}
+		return durations[sorted[i]] > durations[sorted[j]]
+	})
+
+	loads := make([]int64, s.Total)
+	z = []string{}
+	for _, spec := range sorted {
+		shard := 0
+		for i := range loads {
+			if loads[i] < loads[shard] {
+				shard = i
+			}
+		}
+		loads[shard] += durations[spec]
+		if shard == s.Index-1 {
+			z = append(z, spec)
+		}
+	}
+	return
+}
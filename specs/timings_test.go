@@ -0,0 +1,74 @@
+// Package specs resolves the list of specs to run from files, directories and glob patterns
+package specs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimings_read_write(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "timings.json")
+
+	z, err := ReadTimings(path)
+	assert.NoError(err)
+	assert.Equal(Timings{}, z)
+
+	timings := Timings{"a.cy.js": 720000, "b.cy.js": 30000}
+	assert.NoError(timings.Write(path))
+
+	z, err = ReadTimings(path)
+	assert.NoError(err)
+	assert.Equal(timings, z)
+
+	assert.NoError(os.WriteFile(path, []byte("bad"), 0644))
+	_, err = ReadTimings(path)
+	assert.Error(err)
+}
+
+func TestTimings_read_url(t *testing.T) {
+	assert := assert.New(t)
+	os.Setenv("HTTP_RETRY_MAX", "1")
+	defer os.Unsetenv("HTTP_RETRY_MAX")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/timings" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, `{"a.cy.js": 1000}`)
+	}))
+	defer ts.Close()
+
+	z, err := ReadTimings(ts.URL + "/timings")
+	assert.NoError(err)
+	assert.Equal(Timings{"a.cy.js": 1000}, z)
+
+	_, err = ReadTimings(ts.URL + "/notfound")
+	assert.Error(err)
+}
+
+func TestBalance(t *testing.T) {
+	assert := assert.New(t)
+	specs := []string{"a.cy.js", "b.cy.js", "c.cy.js", "d.cy.js", "e.cy.js", "f.cy.js"}
+	timings := Timings{
+		"a.cy.js": 720000,
+		"b.cy.js": 30000,
+		"c.cy.js": 30000,
+		"d.cy.js": 30000,
+		"e.cy.js": 30000,
+	}
+
+	assert.Equal([]string{"a.cy.js"}, Shard{Index: 1, Total: 2}.Balance(specs, timings))
+	assert.Equal([]string{"f.cy.js", "b.cy.js", "c.cy.js", "d.cy.js", "e.cy.js"}, Shard{Index: 2, Total: 2}.Balance(specs, timings))
+
+	// without timings every spec has the same weight
+	assert.Equal([]string{"a.cy.js", "c.cy.js", "e.cy.js"}, Shard{Index: 1, Total: 2}.Balance(specs, Timings{}))
+	assert.Equal([]string{"b.cy.js", "d.cy.js", "f.cy.js"}, Shard{Index: 2, Total: 2}.Balance(specs, nil))
+}
@@ -0,0 +1,309 @@
+// Package xvfb manages the Xvfb servers used by browsers to render specs
+package xvfb
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Lord-Y/cypress-parallel-cli/executor"
+	"github.com/Lord-Y/cypress-parallel-cli/logger"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	lockDir   = "/tmp"           // directory where Xvfb writes .X<n>-lock files
+	socketDir = "/tmp/.X11-unix" // directory where Xvfb creates X<n> sockets
+
+	// command returns the command starting Xvfb on the given display
+	command = func(display string) executor.Command {
+		return executor.Command{
+			Name: "Xvfb",
+			Args: []string{display, "-screen", "0", "1280x1024x24", "-nolisten", "tcp"},
+		}
+	}
+)
+
+const (
+	firstDisplay = 99   // first display number tried
+	maxDisplays  = 1000 // number of display numbers tried before giving up
+)
+
+func init() {
+	logger.SetLoggerLogLevel()
+}
+
+// Manager allocates free displays and keeps track of the servers it started
+type Manager struct {
+	ReadyTimeout time.Duration     // Maximum time to wait for a server to accept connections
+	Executor     executor.Executor // Executor used to start Xvfb
+
+	mu      sync.Mutex
+	used    map[int]bool
+	servers []*Server
+	closed  bool
+}
+
+// Server is an Xvfb server started by a Manager
+type Server struct {
+	Display string // X display like :99
+
+	mu      sync.Mutex
+	manager *Manager
+	number  int
+	process executor.Process
+	exited  chan struct{}
+}
+
+// NewManager returns a Manager with default settings
+func NewManager() *Manager {
+	return &Manager{
+		ReadyTimeout: 10 * time.Second,
+		Executor:     executor.Local{},
+		used:         make(map[int]bool),
+	}
+}
+
+// Required returns false when Xvfb must not be started because DISPLAY
+// is already set or the browser is electron which runs headless unless headed
+func Required(browser string, headed bool) bool {
+	if os.Getenv("DISPLAY") != "" {
+		return false
+	}
+	return browser != "electron" || headed
+}
+
+// Free returns the first n displays that are not locked by another Xvfb.
+// They are the ones a new Manager would pick if nothing changes in the meantime
+func Free(n int) (z []string) {
+	for number := firstDisplay; number < firstDisplay+maxDisplays && len(z) < n; number++ {
+		if !locked(number) {
+			z = append(z, fmt.Sprintf(":%d", number))
+		}
+	}
+	return
+}
+
+// Start picks a free display, starts Xvfb on it and waits until it accepts connections
+func (m *Manager) Start(ctx context.Context) (z *Server, err error) {
+	z = &Server{manager: m}
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	return z, z.start(ctx)
+}
+
+// Close stops every server started by the manager
+func (m *Manager) Close() {
+	m.mu.Lock()
+	m.closed = true
+	servers := m.servers
+	m.servers = nil
+	m.mu.Unlock()
+
+	for _, s := range servers {
+		s.Stop()
+	}
+}
+
+// reserve returns the next display number which is neither used by the manager nor locked by another Xvfb
+func (m *Manager) reserve(from int) (z int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return 0, fmt.Errorf("xvfb manager is closed")
+	}
+	for z = from; z < firstDisplay+maxDisplays; z++ {
+		if m.used[z] || locked(z) {
+			continue
+		}
+		m.used[z] = true
+		return z, nil
+	}
+	return 0, fmt.Errorf("no free display found between :%d and :%d", firstDisplay, firstDisplay+maxDisplays-1)
+}
+
+// release makes the display number available again
+func (m *Manager) release(number int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.used, number)
+}
+
+// track keeps the server so it is stopped by Close.
+// It returns false when the manager is already closed
+func (m *Manager) track(s *Server) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return false
+	}
+	for _, server := range m.servers {
+		if server == s {
+			return true
+		}
+	}
+	m.servers = append(m.servers, s)
+	return true
+}
+
+// start starts Xvfb on the first free display accepting connections.
+// When Xvfb exits before being ready, like when another process took the
+// display in the meantime, the next display is tried
+func (s *Server) start(ctx context.Context) (err error) {
+	number := firstDisplay
+	for {
+		number, err = s.manager.reserve(number)
+		if err != nil {
+			return
+		}
+		s.number = number
+		s.Display = fmt.Sprintf(":%d", number)
+		cmd := command(s.Display)
+		log.Debug().Msgf("Starting Xvfb command %s", cmd)
+		// Xvfb lifetime is bound to the manager, not to the context
+		s.process, err = s.manager.Executor.Start(context.Background(), cmd)
+		if err != nil {
+			s.manager.release(number)
+			return
+		}
+		s.exited = make(chan struct{})
+		go func(process executor.Process, exited chan struct{}) {
+			_, _ = process.Wait()
+			close(exited)
+		}(s.process, s.exited)
+
+		if !s.manager.track(s) {
+			s.stop()
+			return fmt.Errorf("xvfb manager is closed")
+		}
+
+		err = s.waitReady(ctx)
+		if err == nil {
+			log.Debug().Msgf("Xvfb ready on display %s", s.Display)
+			return
+		}
+		s.stop()
+		if err != errExited {
+			return
+		}
+		log.Debug().Msgf("Xvfb exited before being ready on display %s, trying next display", s.Display)
+		number++
+	}
+}
+
+// errExited is returned when Xvfb exits before accepting connections
+var errExited = fmt.Errorf("xvfb exited before accepting connections")
+
+// waitReady waits until the X socket of the server accepts connections
+func (s *Server) waitReady(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.manager.ReadyTimeout)
+	defer cancel()
+
+	socket := filepath.Join(socketDir, fmt.Sprintf("X%d", s.number))
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		conn, err := net.DialTimeout("unix", socket, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		select {
+		case <-s.exited:
+			return errExited
+		case <-ctx.Done():
+			return fmt.Errorf("xvfb not ready on display %s: %w", s.Display, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Running returns true when the Xvfb process is still alive
+func (s *Server) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running()
+}
+
+// running returns true when the Xvfb process is still alive
+func (s *Server) running() bool {
+	if s.process == nil {
+		return false
+	}
+	select {
+	case <-s.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// Ensure restarts the server when it crashed
+func (s *Server) Ensure(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running() {
+		return nil
+	}
+	log.Warn().Msgf("Xvfb on display %s is not running, restarting it", s.Display)
+	s.stop()
+	return s.start(ctx)
+}
+
+// Restart replaces the server by a fresh one, the display may change
+func (s *Server) Restart(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+	return s.start(ctx)
+}
+
+// Stop stops the Xvfb process and releases its display
+func (s *Server) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+}
+
+// stop stops the Xvfb process and releases its display
+func (s *Server) stop() {
+	if s.process == nil {
+		return
+	}
+	_ = s.process.Signal(syscall.SIGTERM)
+	select {
+	case <-s.exited:
+	case <-time.After(5 * time.Second):
+		_ = s.process.Signal(syscall.SIGKILL)
+		<-s.exited
+	}
+	s.process = nil
+	s.manager.release(s.number)
+}
+
+// locked returns true when the display lock file belongs to a running process
+// or the display socket accepts connections
+func locked(number int) bool {
+	if conn, err := net.DialTimeout("unix", filepath.Join(socketDir, fmt.Sprintf("X%d", number)), time.Second); err == nil {
+		conn.Close()
+		return true
+	}
+	content, err := os.ReadFile(filepath.Join(lockDir, fmt.Sprintf(".X%d-lock", number)))
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || pid <= 0 {
+		return true
+	}
+	err = syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,199 @@
+// Package xvfb manages the Xvfb servers used by browsers to render specs
+package xvfb
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Lord-Y/cypress-parallel-cli/executor"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHelperXvfb is not a real test, it behaves like Xvfb when started by fakeXvfb
+func TestHelperXvfb(t *testing.T) {
+	if os.Getenv("XVFB_HELPER") != "1" {
+		return
+	}
+	display := os.Args[len(os.Args)-1]
+	if strings.Contains(os.Getenv("XVFB_HELPER_FAIL"), display+",") {
+		os.Exit(1)
+	}
+	number := strings.TrimPrefix(display, ":")
+	lock := filepath.Join(os.Getenv("XVFB_LOCK_DIR"), fmt.Sprintf(".X%s-lock", number))
+	_ = os.WriteFile(lock, []byte(fmt.Sprintf("%10d\n", os.Getpid())), 0644)
+	socket := filepath.Join(os.Getenv("XVFB_SOCKET_DIR"), "X"+number)
+	os.Remove(socket)
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		os.Exit(1)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGTERM)
+	<-sigc
+	l.Close()
+	os.Remove(lock)
+	os.Exit(0)
+}
+
+// fakeXvfb replaces Xvfb by TestHelperXvfb, displays listed in fail exit immediately
+func fakeXvfb(t *testing.T, fail string) {
+	dir, err := os.MkdirTemp("", "xvfb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldLockDir, oldSocketDir, oldCommand := lockDir, socketDir, command
+	t.Cleanup(func() {
+		lockDir, socketDir, command = oldLockDir, oldSocketDir, oldCommand
+		os.RemoveAll(dir)
+	})
+
+	lockDir = dir
+	socketDir = dir
+	command = func(display string) executor.Command {
+		return executor.Command{
+			Name: os.Args[0],
+			Args: []string{"-test.run=TestHelperXvfb", "--", display},
+			Env: []string{
+				"XVFB_HELPER=1",
+				"XVFB_HELPER_FAIL=" + fail,
+				"XVFB_LOCK_DIR=" + dir,
+				"XVFB_SOCKET_DIR=" + dir,
+			},
+		}
+	}
+}
+
+func TestRequired(t *testing.T) {
+	assert := assert.New(t)
+	display := os.Getenv("DISPLAY")
+	defer os.Setenv("DISPLAY", display)
+
+	os.Unsetenv("DISPLAY")
+	assert.True(Required("chrome", false))
+	assert.False(Required("electron", false))
+	assert.True(Required("electron", true))
+
+	os.Setenv("DISPLAY", ":0")
+	assert.False(Required("chrome", false))
+	assert.False(Required("electron", true))
+}
+
+func TestManager(t *testing.T) {
+	assert := assert.New(t)
+	fakeXvfb(t, ":100,")
+	// a display locked by a running process is skipped
+	assert.NoError(os.WriteFile(filepath.Join(lockDir, ".X99-lock"), []byte(fmt.Sprintf("%10d\n", os.Getpid())), 0644))
+
+	m := NewManager()
+	s1, err := m.Start(context.Background())
+	assert.NoError(err)
+	assert.Equal(":101", s1.Display)
+	assert.True(s1.Running())
+
+	s2, err := m.Start(context.Background())
+	assert.NoError(err)
+	assert.Equal(":102", s2.Display)
+
+	// a crashed server is restarted
+	assert.NoError(s1.process.Signal(syscall.SIGKILL))
+	<-s1.exited
+	assert.False(s1.Running())
+	assert.NoError(s1.Ensure(context.Background()))
+	assert.True(s1.Running())
+	assert.Equal(":101", s1.Display)
+
+	assert.NoError(s2.Restart(context.Background()))
+	assert.True(s2.Running())
+
+	m.Close()
+	assert.False(s1.Running())
+	assert.False(s2.Running())
+	_, err = m.Start(context.Background())
+	assert.Error(err)
+}
+
+func TestManager_not_ready(t *testing.T) {
+	assert := assert.New(t)
+	fakeXvfb(t, "")
+	command = func(display string) executor.Command {
+		return executor.Command{Name: "sleep", Args: []string{"10"}}
+	}
+
+	m := NewManager()
+	m.ReadyTimeout = 200 * time.Millisecond
+	defer m.Close()
+	_, err := m.Start(context.Background())
+	assert.Error(err)
+}
+
+func TestLocked(t *testing.T) {
+	assert := assert.New(t)
+	fakeXvfb(t, "")
+
+	assert.False(locked(99))
+
+	// stale lock of a process that does not exist anymore
+	assert.NoError(os.WriteFile(filepath.Join(lockDir, ".X99-lock"), []byte(fmt.Sprintf("%10d\n", 1<<30)), 0644))
+	assert.False(locked(99))
+
+	assert.NoError(os.WriteFile(filepath.Join(lockDir, ".X99-lock"), []byte(fmt.Sprintf("%10d\n", os.Getpid())), 0644))
+	assert.True(locked(99))
+
+	// stale socket of a crashed server
+	assert.NoError(os.WriteFile(filepath.Join(socketDir, "X100"), []byte(""), 0644))
+	assert.False(locked(100))
+
+	l, err := net.Listen("unix", filepath.Join(socketDir, "X101"))
+	assert.NoError(err)
+	defer l.Close()
+	assert.True(locked(101))
+}
+
+func TestFree(t *testing.T) {
+	assert := assert.New(t)
+	fakeXvfb(t, "")
+
+	assert.Empty(Free(0))
+	assert.Equal([]string{":99", ":100"}, Free(2))
+
+	assert.NoError(os.WriteFile(filepath.Join(lockDir, ".X99-lock"), []byte(fmt.Sprintf("%10d\n", os.Getpid())), 0644))
+	assert.Equal([]string{":100", ":101"}, Free(2))
+}
+
+func TestManager_executor(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("Xvfb :99 -screen 0 1280x1024x24 -nolisten tcp", command(":99").String())
+	fakeXvfb(t, "")
+	r := &executor.Recorder{
+		Handler: func(ctx context.Context, cmd executor.Command) ([]byte, error) {
+			return nil, fmt.Errorf("Xvfb: fatal server error")
+		},
+	}
+
+	m := NewManager()
+	m.Executor = r
+	defer m.Close()
+	_, err := m.Start(context.Background())
+	assert.Error(err)
+	commands := r.Commands()
+	assert.Len(commands, maxDisplays)
+	assert.Equal([]string{"--", ":99"}, commands[0].Args[1:])
+	assert.Equal([]string{"--", ":100"}, commands[1].Args[1:])
+}